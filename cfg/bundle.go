@@ -0,0 +1,147 @@
+package cfg
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// 打包文件格式(小端):
+//
+//	magic       [4]byte "XCFG"
+//	version     uint32
+//	tableCount  uint32
+//	table directory, tableCount个:
+//	  nameLen   uint16
+//	  name      [nameLen]byte
+//	  offset    uint64 相对于文件开头
+//	  length    uint64
+//	  hash      [16]byte 数据的md5
+//	table data
+const (
+	BundleMagic   = "XCFG"
+	BundleVersion = 1
+)
+
+var (
+	ErrBundleFormat        = errors.New("invalid bundle format")
+	ErrBundleVersion       = errors.New("unsupported bundle version")
+	ErrBundleTableNotFound = errors.New("bundle table not found")
+	ErrBundleTableHash     = errors.New("bundle table hash mismatch")
+)
+
+// 打包文件中的一个配置表
+type BundleTable struct {
+	Name   string // 导出的文件名,如Quests.pb
+	Offset uint64
+	Length uint64
+	Hash   [md5.Size]byte
+}
+
+// 把多个配置表打包在一个文件里,减少文件数量
+type Bundle struct {
+	fileName string
+	data     []byte
+	tables   []*BundleTable
+	tableMap map[string]*BundleTable
+}
+
+// 打开打包文件,整个文件读入内存,压缩的打包文件(如cfg.bundle.gz)自动解压
+func OpenBundle(fileName string) (*Bundle, error) {
+	_, fileData, err := ReadDataFile(fileName)
+	if err != nil {
+		slog.Error("OpenBundleErr", "fileName", fileName, "err", err)
+		return nil, err
+	}
+	bundle, err := ParseBundle(fileData)
+	if err != nil {
+		slog.Error("OpenBundleErr", "fileName", fileName, "err", err)
+		return nil, err
+	}
+	bundle.fileName = fileName
+	return bundle, nil
+}
+
+// 解析打包数据,只解析目录,读取配置表时才校验hash
+func ParseBundle(data []byte) (*Bundle, error) {
+	reader := bytes.NewReader(data)
+	magic := make([]byte, len(BundleMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != BundleMagic {
+		return nil, ErrBundleFormat
+	}
+	var version, tableCount uint32
+	if err := binary.Read(reader, binary.LittleEndian, &version); err != nil {
+		return nil, ErrBundleFormat
+	}
+	if version != BundleVersion {
+		return nil, fmt.Errorf("%w: %v", ErrBundleVersion, version)
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &tableCount); err != nil {
+		return nil, ErrBundleFormat
+	}
+	bundle := &Bundle{
+		data:     data,
+		tableMap: make(map[string]*BundleTable),
+	}
+	for i := uint32(0); i < tableCount; i++ {
+		var nameLen uint16
+		if err := binary.Read(reader, binary.LittleEndian, &nameLen); err != nil {
+			return nil, ErrBundleFormat
+		}
+		name := make([]byte, nameLen)
+		if _, err := io.ReadFull(reader, name); err != nil {
+			return nil, ErrBundleFormat
+		}
+		table := &BundleTable{
+			Name: string(name),
+		}
+		if err := binary.Read(reader, binary.LittleEndian, &table.Offset); err != nil {
+			return nil, ErrBundleFormat
+		}
+		if err := binary.Read(reader, binary.LittleEndian, &table.Length); err != nil {
+			return nil, ErrBundleFormat
+		}
+		if _, err := io.ReadFull(reader, table.Hash[:]); err != nil {
+			return nil, ErrBundleFormat
+		}
+		if table.Offset > uint64(len(data)) || table.Length > uint64(len(data))-table.Offset {
+			return nil, fmt.Errorf("%w: table %v out of range", ErrBundleFormat, table.Name)
+		}
+		bundle.tables = append(bundle.tables, table)
+		bundle.tableMap[table.Name] = table
+	}
+	return bundle, nil
+}
+
+func (this *Bundle) FileName() string {
+	return this.fileName
+}
+
+// 所有的配置表,和导出的顺序一致
+func (this *Bundle) Tables() []*BundleTable {
+	return this.tables
+}
+
+// 读取一个配置表的数据,并校验hash
+func (this *Bundle) ReadTable(name string) ([]byte, error) {
+	table, ok := this.tableMap[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrBundleTableNotFound, name)
+	}
+	tableData := this.data[table.Offset : table.Offset+table.Length]
+	if md5.Sum(tableData) != table.Hash {
+		return nil, fmt.Errorf("%w: %v", ErrBundleTableHash, name)
+	}
+	return tableData, nil
+}
+
+// 实现DataSource,打包文件里的配置表都是pb格式
+func (this *Bundle) ReadFile(fileName string) (string, []byte, error) {
+	tableName := EnsureDataFileExt(fileName, ".pb")
+	tableData, err := this.ReadTable(tableName)
+	return tableName, tableData, err
+}
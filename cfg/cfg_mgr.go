@@ -1,8 +1,7 @@
 package cfg
 
 import (
-	"bufio"
-	"encoding/json"
+	"bytes"
 	"errors"
 	"fmt"
 	"google.golang.org/protobuf/encoding/protodelim"
@@ -49,29 +48,48 @@ func (this *DataMap[E]) Range(f func(e E) bool) {
 	}
 }
 
-// 加载配置数据,支持json和csv
+// 加载配置数据,支持json和pb
 func (this *DataMap[E]) Load(fileName string) error {
+	fileData, err := os.ReadFile(fileName)
+	if err != nil {
+		slog.Error("LoadErr", "fileName", fileName, "err", err)
+		return err
+	}
+	return this.LoadData(fileName, fileData)
+}
+
+// 从已经读取的数据加载,根据文件扩展名区分json和pb,压缩文件自动解压
+func (this *DataMap[E]) LoadData(fileName string, fileData []byte) error {
 	if this.cfgs == nil {
 		this.cfgs = make(map[int32]E)
 	}
+	fileName, fileData, err := DecompressData(fileName, fileData)
+	if err != nil {
+		slog.Error("LoadErr", "fileName", fileName, "err", err)
+		return err
+	}
 	if strings.HasSuffix(fileName, ".json") {
-		return this.LoadJson(fileName)
+		return this.loadJsonData(fileName, fileData)
 	}
 	if strings.HasSuffix(fileName, ".pb") {
-		return this.LoadPb(fileName)
+		return this.loadPbData(fileName, fileData)
 	}
 	return errors.New("unsupported file type")
 }
 
 // 从json文件加载数据
 func (this *DataMap[E]) LoadJson(fileName string) error {
-	fileData, err := os.ReadFile(fileName)
+	fileName, fileData, err := ReadDataFile(fileName)
 	if err != nil {
 		slog.Error("LoadJsonErr", "fileName", fileName, "err", err)
 		return err
 	}
+	return this.loadJsonData(fileName, fileData)
+}
+
+func (this *DataMap[E]) loadJsonData(fileName string, fileData []byte) error {
 	cfgMap := make(map[int32]E)
-	err = json.Unmarshal(fileData, &cfgMap)
+	err := unmarshalJsonMap(fileData, &cfgMap)
 	if err != nil {
 		slog.Error("LoadJsonErr", "fileName", fileName, "err", err)
 		return err
@@ -83,13 +101,22 @@ func (this *DataMap[E]) LoadJson(fileName string) error {
 
 // 从pb文件加载数据
 func (this *DataMap[E]) LoadPb(fileName string) error {
-	file, err := os.Open(fileName)
+	fileName, fileData, err := ReadDataFile(fileName)
+	if err != nil {
+		slog.Error("LoadPbErr", "fileName", fileName, "err", err)
+		return err
+	}
+	return this.loadPbData(fileName, fileData)
+}
+
+// 加密的数据先解密
+func (this *DataMap[E]) loadPbData(fileName string, fileData []byte) error {
+	fileData, err := DecryptData(fileName, fileData)
 	if err != nil {
 		slog.Error("LoadPbErr", "fileName", fileName, "err", err)
 		return err
 	}
-	defer file.Close()
-	reader := bufio.NewReader(file)
+	reader := bytes.NewReader(fileData)
 	cfgMap := make(map[int32]E)
 	for {
 		cfg, newErr := newElement[E]()
@@ -137,26 +164,45 @@ func (this *DataSlice[E]) Range(f func(e E) bool) {
 	}
 }
 
-// 加载配置数据,支持json和csv
+// 加载配置数据,支持json和pb
 func (this *DataSlice[E]) Load(fileName string) error {
+	fileData, err := os.ReadFile(fileName)
+	if err != nil {
+		slog.Error("LoadErr", "fileName", fileName, "err", err)
+		return err
+	}
+	return this.LoadData(fileName, fileData)
+}
+
+// 从已经读取的数据加载,根据文件扩展名区分json和pb,压缩文件自动解压
+func (this *DataSlice[E]) LoadData(fileName string, fileData []byte) error {
+	fileName, fileData, err := DecompressData(fileName, fileData)
+	if err != nil {
+		slog.Error("LoadErr", "fileName", fileName, "err", err)
+		return err
+	}
 	if strings.HasSuffix(fileName, ".json") {
-		return this.LoadJson(fileName)
+		return this.loadJsonData(fileName, fileData)
 	}
 	if strings.HasSuffix(fileName, ".pb") {
-		return this.LoadPb(fileName)
+		return this.loadPbData(fileName, fileData)
 	}
 	return errors.New("unsupported file type")
 }
 
 // 从json文件加载数据
 func (this *DataSlice[E]) LoadJson(fileName string) error {
-	fileData, err := os.ReadFile(fileName)
+	fileName, fileData, err := ReadDataFile(fileName)
 	if err != nil {
 		slog.Error("LoadJsonErr", "fileName", fileName, "err", err)
 		return err
 	}
+	return this.loadJsonData(fileName, fileData)
+}
+
+func (this *DataSlice[E]) loadJsonData(fileName string, fileData []byte) error {
 	var cfgList []E
-	err = json.Unmarshal(fileData, &cfgList)
+	err := unmarshalJsonList(fileData, &cfgList)
 	if err != nil {
 		slog.Error("LoadJsonErr", "fileName", fileName, "err", err)
 		return err
@@ -169,13 +215,22 @@ func (this *DataSlice[E]) LoadJson(fileName string) error {
 
 // 从pb文件加载数据
 func (this *DataSlice[E]) LoadPb(fileName string) error {
-	file, err := os.Open(fileName)
+	fileName, fileData, err := ReadDataFile(fileName)
 	if err != nil {
 		slog.Error("LoadPbErr", "fileName", fileName, "err", err)
 		return err
 	}
-	defer file.Close()
-	reader := bufio.NewReader(file)
+	return this.loadPbData(fileName, fileData)
+}
+
+// 加密的数据先解密
+func (this *DataSlice[E]) loadPbData(fileName string, fileData []byte) error {
+	fileData, err := DecryptData(fileName, fileData)
+	if err != nil {
+		slog.Error("LoadPbErr", "fileName", fileName, "err", err)
+		return err
+	}
+	reader := bytes.NewReader(fileData)
 	var cfgList []E
 	for {
 		cfg, newErr := newElement[E]()
@@ -225,21 +280,32 @@ func LoadObjectFromJson(fileName string, obj proto.Message) error {
 		slog.Error("LoadObjectFromFileErr", "fileName", fileName, "err", err)
 		return err
 	}
-	err = protojson.Unmarshal(fileData, obj)
+	return loadObjectData(fileName, fileData, obj)
+}
+
+func LoadObjectFromPb(fileName string, obj proto.Message) error {
+	fileData, err := os.ReadFile(fileName)
 	if err != nil {
 		slog.Error("LoadObjectFromFileErr", "fileName", fileName, "err", err)
 		return err
 	}
-	return nil
+	return loadObjectData(fileName, fileData, obj)
 }
 
-func LoadObjectFromPb(fileName string, obj proto.Message) error {
-	fileData, err := os.ReadFile(fileName)
+// 根据文件扩展名区分json和pb,压缩文件自动解压,加密的pb数据自动解密
+func loadObjectData(fileName string, fileData []byte, obj proto.Message) error {
+	fileName, fileData, err := DecompressData(fileName, fileData)
 	if err != nil {
 		slog.Error("LoadObjectFromFileErr", "fileName", fileName, "err", err)
 		return err
 	}
-	err = proto.Unmarshal(fileData, obj)
+	if strings.HasSuffix(fileName, ".pb") {
+		if fileData, err = DecryptData(fileName, fileData); err == nil {
+			err = proto.Unmarshal(fileData, obj)
+		}
+	} else {
+		err = protojson.Unmarshal(fileData, obj)
+	}
 	if err != nil {
 		slog.Error("LoadObjectFromFileErr", "fileName", fileName, "err", err)
 		return err
@@ -247,6 +313,15 @@ func LoadObjectFromPb(fileName string, obj proto.Message) error {
 	return nil
 }
 
+// 读取配置数据文件,压缩文件自动解压,返回去掉压缩扩展名的文件名
+func ReadDataFile(fileName string) (string, []byte, error) {
+	fileData, err := os.ReadFile(fileName)
+	if err != nil {
+		return fileName, nil, err
+	}
+	return DecompressData(fileName, fileData)
+}
+
 func ResolveDataFile(fileName string) string {
 	return EnsureDataFileExt(fileName, DataFileExt)
 }
@@ -278,36 +353,83 @@ func newElement[E any]() (E, error) {
 	return elem, nil
 }
 
+// 配置数据的来源,如数据目录,打包文件
+type DataSource interface {
+	// 读取配置数据,fileName是导出的文件名(如Quests.json)
+	// 返回实际读取的文件名(扩展名用于区分json和pb)和数据
+	ReadFile(fileName string) (string, []byte, error)
+}
+
+// 数据目录,根据DataFileExt确定读取的文件
+type DirSource struct {
+	dataDir string
+}
+
+func NewDirSource(dataDir string) *DirSource {
+	return &DirSource{
+		dataDir: dataDir,
+	}
+}
+
+// 文件不存在时,查找压缩后的文件,如Quests.pb.gz
+func (this *DirSource) ReadFile(fileName string) (string, []byte, error) {
+	resolvedFileName := ResolveDataFile(this.dataDir + fileName)
+	fileData, err := os.ReadFile(resolvedFileName)
+	if err != nil && os.IsNotExist(err) {
+		for _, compressExt := range CompressExts {
+			compressedData, compressedErr := os.ReadFile(resolvedFileName + compressExt)
+			if compressedErr == nil {
+				return resolvedFileName + compressExt, compressedData, nil
+			}
+		}
+	}
+	return resolvedFileName, fileData, err
+}
+
 type loadable interface {
-	Load(filename string) error
+	LoadData(fileName string, fileData []byte) error
 }
 
+// 从目录加载配置表
 func LoadConfig[L loadable](filter func(string) bool, fileName, dataDir string, newFn func() L, target *L) error {
+	return LoadConfigFrom(filter, fileName, NewDirSource(dataDir), newFn, target)
+}
+
+// 从数据源(目录或者打包文件)加载配置表
+func LoadConfigFrom[L loadable](filter func(string) bool, fileName string, source DataSource, newFn func() L, target *L) error {
 	if filter != nil && !filter(fileName) {
 		return nil
 	}
-	resolvedFileName := ResolveDataFile(dataDir + fileName)
+	resolvedFileName, fileData, err := source.ReadFile(fileName)
+	if err != nil {
+		slog.Error("LoadConfigErr", "fileName", resolvedFileName, "err", err)
+		return err
+	}
 	tmp := newFn()
-	if err := tmp.Load(resolvedFileName); err != nil {
+	if err = tmp.LoadData(resolvedFileName, fileData); err != nil {
 		return err
 	}
 	*target = tmp
 	return nil
 }
 
+// 从目录加载object类型的配置
 func LoadObjectConfig[T proto.Message](filter func(string) bool, fileName, dataDir string, newFn func() T, target *T) error {
+	return LoadObjectConfigFrom(filter, fileName, NewDirSource(dataDir), newFn, target)
+}
+
+// 从数据源(目录或者打包文件)加载object类型的配置
+func LoadObjectConfigFrom[T proto.Message](filter func(string) bool, fileName string, source DataSource, newFn func() T, target *T) error {
 	if filter != nil && !filter(fileName) {
 		return nil
 	}
-	resolvedFileName := ResolveDataFile(dataDir + fileName)
-	tmp := newFn()
-	var err error
-	if strings.HasSuffix(resolvedFileName, ".pb") {
-		err = LoadObjectFromPb(resolvedFileName, tmp)
-	} else {
-		err = LoadObjectFromJson(resolvedFileName, tmp)
-	}
+	resolvedFileName, fileData, err := source.ReadFile(fileName)
 	if err != nil {
+		slog.Error("LoadConfigErr", "fileName", resolvedFileName, "err", err)
+		return err
+	}
+	tmp := newFn()
+	if err = loadObjectData(resolvedFileName, fileData, tmp); err != nil {
 		return err
 	}
 	*target = tmp
@@ -2,6 +2,8 @@ package cfg
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"os"
 	"path/filepath"
 	"testing"
@@ -9,6 +11,9 @@ import (
 	"excelexporter/example/pb"
 	"google.golang.org/protobuf/encoding/protodelim"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestResolveDataFile(t *testing.T) {
@@ -107,3 +112,165 @@ func TestLoadObjectFromPb(t *testing.T) {
 		t.Fatalf("unexpected object data: %+v", dst)
 	}
 }
+
+func TestLoadCompressedPb(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	for _, msg := range []*pb.QuestCfg{{CfgId: 1, Name: "A"}, {CfgId: 2, Name: "B"}} {
+		if _, err := protodelim.MarshalTo(buffer, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gzBuffer := bytes.NewBuffer(nil)
+	gzWriter := gzip.NewWriter(gzBuffer)
+	if _, err := gzWriter.Write(buffer.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dataDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, "Quests.pb.gz"), gzBuffer.Bytes(), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := NewDataMap[*pb.QuestCfg]()
+	if err := mgr.Load(filepath.Join(dataDir, "Quests.pb.gz")); err != nil {
+		t.Fatal(err)
+	}
+	if len(mgr.cfgs) != 2 || mgr.cfgs[2].Name != "B" {
+		t.Fatalf("unexpected map data: %+v", mgr.cfgs)
+	}
+
+	// 数据目录中只有压缩文件时,自动查找
+	DataFileExt = ".pb"
+	defer func() { DataFileExt = ".json" }()
+	var quests *DataMap[*pb.QuestCfg]
+	if err := LoadConfig(nil, "Quests.json", dataDir+"/", NewDataMap[*pb.QuestCfg], &quests); err != nil {
+		t.Fatal(err)
+	}
+	if quests.GetCfg(1).GetName() != "A" {
+		t.Fatalf("unexpected map data: %+v", quests.cfgs)
+	}
+
+	// 压缩数据损坏
+	if err := mgr.LoadData("Quests.pb.gz", gzBuffer.Bytes()[:10]); err == nil {
+		t.Fatal("expected error for corrupted data")
+	}
+}
+
+func TestDataKeyMapLoad(t *testing.T) {
+	type questKey struct {
+		QuestType int32
+		CfgId     int32
+	}
+	newMgr := func() *DataKeyMap[questKey, *pb.QuestCfg] {
+		return NewDataKeyMap[questKey, *pb.QuestCfg](func(cfg *pb.QuestCfg) questKey {
+			return questKey{QuestType: cfg.GetQuestType(), CfgId: cfg.GetCfgId()}
+		})
+	}
+	// json的key是导出工具生成的组合key字符串
+	jsonData := []byte(`{"1_1":{"CfgId":1,"QuestType":1,"Name":"A"},"2_1":{"CfgId":1,"QuestType":2,"Name":"B"}}`)
+	mgr := newMgr()
+	if err := mgr.LoadData("quest.json", jsonData); err != nil {
+		t.Fatal(err)
+	}
+	if mgr.Len() != 2 || mgr.GetCfg(questKey{QuestType: 2, CfgId: 1}).GetName() != "B" {
+		t.Fatalf("unexpected json data: %+v", mgr.cfgs)
+	}
+
+	var buffer bytes.Buffer
+	for _, msg := range []*pb.QuestCfg{{CfgId: 1, QuestType: 1, Name: "A"}, {CfgId: 1, QuestType: 2, Name: "B"}} {
+		if _, err := protodelim.MarshalTo(&buffer, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mgr = newMgr()
+	if err := mgr.LoadData("quest.pb", buffer.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if mgr.Len() != 2 || mgr.GetCfg(questKey{QuestType: 1, CfgId: 1}).GetName() != "A" {
+		t.Fatalf("unexpected pb data: %+v", mgr.cfgs)
+	}
+	if mgr.GetCfg(questKey{QuestType: 3, CfgId: 1}) != nil {
+		t.Fatal("expected nil cfg")
+	}
+}
+
+func TestDataGroupLoad(t *testing.T) {
+	newMgr := func() *DataGroup[int32, *pb.QuestCfg] {
+		return NewDataGroup[int32, *pb.QuestCfg](func(cfg *pb.QuestCfg) int32 {
+			return cfg.GetQuestType()
+		})
+	}
+	checkFn := func(mgr *DataGroup[int32, *pb.QuestCfg]) {
+		t.Helper()
+		group := mgr.GetGroup(1)
+		if mgr.Len() != 2 || len(group) != 2 || group[0].GetCfgId() != 1 || group[1].GetCfgId() != 3 {
+			t.Fatalf("unexpected group data: %+v", mgr.groups)
+		}
+		var cfgIds []int32
+		mgr.RangeGroup(2, func(cfg *pb.QuestCfg) bool {
+			cfgIds = append(cfgIds, cfg.GetCfgId())
+			return true
+		})
+		if len(cfgIds) != 1 || cfgIds[0] != 2 || mgr.GetGroup(3) != nil {
+			t.Fatalf("unexpected group 2: %v", cfgIds)
+		}
+	}
+	jsonData := []byte(`{"1":[{"CfgId":1,"QuestType":1},{"CfgId":3,"QuestType":1}],"2":[{"CfgId":2,"QuestType":2}]}`)
+	mgr := newMgr()
+	if err := mgr.LoadData("quest.json", jsonData); err != nil {
+		t.Fatal(err)
+	}
+	checkFn(mgr)
+
+	var buffer bytes.Buffer
+	for _, msg := range []*pb.QuestCfg{{CfgId: 1, QuestType: 1}, {CfgId: 3, QuestType: 1}, {CfgId: 2, QuestType: 2}} {
+		if _, err := protodelim.MarshalTo(&buffer, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mgr = newMgr()
+	if err := mgr.LoadData("quest.pb", buffer.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	checkFn(mgr)
+}
+
+func TestLoadJsonWellKnownTypes(t *testing.T) {
+	// 普通的配置表使用encoding/json,well-known types使用protojson
+	if useProtoJson[*pb.QuestCfg]() {
+		t.Fatal("QuestCfg should use encoding/json")
+	}
+	if !useProtoJson[*structpb.Struct]() {
+		t.Fatal("Struct should use protojson")
+	}
+	slice := &DataSlice[*structpb.Struct]{}
+	if err := slice.LoadData("struct.json", []byte(`[{"a":1,"b":"x"},{"c":[true]}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(slice.cfgs) != 2 || slice.cfgs[0].GetFields()["a"].GetNumberValue() != 1 || slice.cfgs[0].GetFields()["b"].GetStringValue() != "x" {
+		t.Fatalf("unexpected struct data: %v", slice.cfgs)
+	}
+	group := NewDataGroup[int64, *durationpb.Duration](func(d *durationpb.Duration) int64 {
+		return d.GetSeconds() / 3600
+	})
+	if err := group.LoadData("duration.json", []byte(`{"1":["5400s","3600s"],"2":["7200.5s"]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(group.GetGroup(1)) != 2 || group.GetGroup(2)[0].GetNanos() != 500000000 {
+		t.Fatalf("unexpected duration data: %v", group.groups)
+	}
+}
+
+func TestLoadJsonBytes(t *testing.T) {
+	// 导出工具把bytes字段导出为base64
+	slice := &DataSlice[*wrapperspb.BytesValue]{}
+	if err := slice.LoadData("bytes.json", []byte(`["aGVsbG8=","3q2+7w=="]`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(slice.cfgs) != 2 || string(slice.cfgs[0].GetValue()) != "hello" ||
+		!bytes.Equal(slice.cfgs[1].GetValue(), []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Fatalf("unexpected bytes data: %v", slice.cfgs)
+	}
+}
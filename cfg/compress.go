@@ -0,0 +1,44 @@
+package cfg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 压缩文件的扩展名,导出工具配置了Compress时,导出的文件名会加上对应的扩展名,如Quests.pb.gz
+var CompressExts = []string{".gz", ".zst"}
+
+// 根据扩展名解压数据,返回去掉压缩扩展名的文件名和解压后的数据
+// 不是压缩文件时,直接返回原数据
+func DecompressData(fileName string, fileData []byte) (string, []byte, error) {
+	switch {
+	case strings.HasSuffix(fileName, ".gz"):
+		reader, err := gzip.NewReader(bytes.NewReader(fileData))
+		if err != nil {
+			return fileName, nil, fmt.Errorf("gzip decompress %v err:%w", fileName, err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return fileName, nil, fmt.Errorf("gzip decompress %v err:%w", fileName, err)
+		}
+		return strings.TrimSuffix(fileName, ".gz"), data, nil
+	case strings.HasSuffix(fileName, ".zst"):
+		decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return fileName, nil, err
+		}
+		defer decoder.Close()
+		data, err := decoder.DecodeAll(fileData, nil)
+		if err != nil {
+			return fileName, nil, fmt.Errorf("zstd decompress %v err:%w", fileName, err)
+		}
+		return strings.TrimSuffix(fileName, ".zst"), data, nil
+	}
+	return fileName, fileData, nil
+}
@@ -0,0 +1,164 @@
+package cfg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// 一对多的配置数据管理,一个key对应多个配置项,如每个掉落id对应的所有掉落项
+// key由keyFn从配置项中获取,同一个key的配置项保持导出时的顺序
+type DataGroup[K comparable, E proto.Message] struct {
+	groups map[K][]E
+	keyFn  func(e E) K
+}
+
+// keyFn:获取配置项的key,如func(cfg *pb.DropCfg) int32 { return cfg.GetDropId() }
+func NewDataGroup[K comparable, E proto.Message](keyFn func(e E) K) *DataGroup[K, E] {
+	return &DataGroup[K, E]{
+		groups: make(map[K][]E),
+		keyFn:  keyFn,
+	}
+}
+
+// 获取key对应的所有配置项,不要修改返回的slice
+func (this *DataGroup[K, E]) GetGroup(key K) []E {
+	return this.groups[key]
+}
+
+// key的数量
+func (this *DataGroup[K, E]) Len() int {
+	return len(this.groups)
+}
+
+// 遍历所有分组
+func (this *DataGroup[K, E]) Range(f func(key K, group []E) bool) {
+	for key, group := range this.groups {
+		if !f(key, group) {
+			return
+		}
+	}
+}
+
+// 按顺序遍历key对应的配置项
+func (this *DataGroup[K, E]) RangeGroup(key K, f func(e E) bool) {
+	for _, cfg := range this.groups[key] {
+		if !f(cfg) {
+			return
+		}
+	}
+}
+
+// 遍历所有配置项
+func (this *DataGroup[K, E]) RangeAll(f func(key K, e E) bool) {
+	for key, group := range this.groups {
+		for _, cfg := range group {
+			if !f(key, cfg) {
+				return
+			}
+		}
+	}
+}
+
+// 加载配置数据,支持json和pb
+func (this *DataGroup[K, E]) Load(fileName string) error {
+	fileData, err := os.ReadFile(fileName)
+	if err != nil {
+		slog.Error("LoadErr", "fileName", fileName, "err", err)
+		return err
+	}
+	return this.LoadData(fileName, fileData)
+}
+
+// 从已经读取的数据加载,根据文件扩展名区分json和pb,压缩文件自动解压
+func (this *DataGroup[K, E]) LoadData(fileName string, fileData []byte) error {
+	fileName, fileData, err := DecompressData(fileName, fileData)
+	if err != nil {
+		slog.Error("LoadErr", "fileName", fileName, "err", err)
+		return err
+	}
+	if strings.HasSuffix(fileName, ".json") {
+		return this.loadJsonData(fileName, fileData)
+	}
+	if strings.HasSuffix(fileName, ".pb") {
+		return this.loadPbData(fileName, fileData)
+	}
+	return errors.New("unsupported file type")
+}
+
+// 从json文件加载数据
+func (this *DataGroup[K, E]) LoadJson(fileName string) error {
+	fileName, fileData, err := ReadDataFile(fileName)
+	if err != nil {
+		slog.Error("LoadJsonErr", "fileName", fileName, "err", err)
+		return err
+	}
+	return this.loadJsonData(fileName, fileData)
+}
+
+// json数据格式:{"key":[{...},{...}]},加载时使用keyFn重新计算key
+func (this *DataGroup[K, E]) loadJsonData(fileName string, fileData []byte) error {
+	var jsonMap map[string][]E
+	err := unmarshalJsonGroup(fileData, &jsonMap)
+	if err != nil {
+		slog.Error("LoadJsonErr", "fileName", fileName, "err", err)
+		return err
+	}
+	groups := make(map[K][]E, len(jsonMap))
+	for _, group := range jsonMap {
+		for _, cfg := range group {
+			key := this.keyFn(cfg)
+			groups[key] = append(groups[key], cfg)
+		}
+	}
+	this.groups = groups
+	slog.Info("LoadJson", "fileName", fileName, "count", len(this.groups))
+	return nil
+}
+
+// 从pb文件加载数据
+func (this *DataGroup[K, E]) LoadPb(fileName string) error {
+	fileName, fileData, err := ReadDataFile(fileName)
+	if err != nil {
+		slog.Error("LoadPbErr", "fileName", fileName, "err", err)
+		return err
+	}
+	return this.loadPbData(fileName, fileData)
+}
+
+// pb数据是按key排序的所有配置项,加密的数据先解密
+func (this *DataGroup[K, E]) loadPbData(fileName string, fileData []byte) error {
+	fileData, err := DecryptData(fileName, fileData)
+	if err != nil {
+		slog.Error("LoadPbErr", "fileName", fileName, "err", err)
+		return err
+	}
+	reader := bytes.NewReader(fileData)
+	groups := make(map[K][]E)
+	for {
+		cfg, newErr := newElement[E]()
+		if newErr != nil {
+			slog.Error("LoadPbErr", "fileName", fileName, "err", newErr)
+			return newErr
+		}
+		err = protodelim.UnmarshalFrom(reader, cfg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			slog.Error("LoadPbErr", "fileName", fileName, "err", err)
+			return err
+		}
+		key := this.keyFn(cfg)
+		groups[key] = append(groups[key], cfg)
+	}
+	this.groups = groups
+	slog.Info("LoadPb", "fileName", fileName, "count", len(this.groups))
+	return nil
+}
@@ -0,0 +1,149 @@
+package cfg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// 自定义key类型的配置数据管理,如string类型的key,多个字段组成的组合key
+// key由keyFn从配置项中获取,所以json和pb数据中的key格式不影响加载
+type DataKeyMap[K comparable, E proto.Message] struct {
+	cfgs  map[K]E
+	keyFn func(e E) K
+}
+
+// keyFn:获取配置项的key,如组合key:
+//
+//	func(cfg *pb.ActivityStageCfg) ActivityStageCfgKey {
+//		return ActivityStageCfgKey{ActivityId: cfg.GetActivityId(), Stage: cfg.GetStage()}
+//	}
+func NewDataKeyMap[K comparable, E proto.Message](keyFn func(e E) K) *DataKeyMap[K, E] {
+	return &DataKeyMap[K, E]{
+		cfgs:  make(map[K]E),
+		keyFn: keyFn,
+	}
+}
+
+func (this *DataKeyMap[K, E]) GetCfg(key K) E {
+	return this.cfgs[key]
+}
+
+func (this *DataKeyMap[K, E]) Len() int {
+	return len(this.cfgs)
+}
+
+func (this *DataKeyMap[K, E]) Range(f func(key K, e E) bool) {
+	for key, cfg := range this.cfgs {
+		if !f(key, cfg) {
+			return
+		}
+	}
+}
+
+// 加载配置数据,支持json和pb
+func (this *DataKeyMap[K, E]) Load(fileName string) error {
+	fileData, err := os.ReadFile(fileName)
+	if err != nil {
+		slog.Error("LoadErr", "fileName", fileName, "err", err)
+		return err
+	}
+	return this.LoadData(fileName, fileData)
+}
+
+// 从已经读取的数据加载,根据文件扩展名区分json和pb,压缩文件自动解压
+func (this *DataKeyMap[K, E]) LoadData(fileName string, fileData []byte) error {
+	fileName, fileData, err := DecompressData(fileName, fileData)
+	if err != nil {
+		slog.Error("LoadErr", "fileName", fileName, "err", err)
+		return err
+	}
+	if strings.HasSuffix(fileName, ".json") {
+		return this.loadJsonData(fileName, fileData)
+	}
+	if strings.HasSuffix(fileName, ".pb") {
+		return this.loadPbData(fileName, fileData)
+	}
+	return errors.New("unsupported file type")
+}
+
+// 从json文件加载数据
+func (this *DataKeyMap[K, E]) LoadJson(fileName string) error {
+	fileName, fileData, err := ReadDataFile(fileName)
+	if err != nil {
+		slog.Error("LoadJsonErr", "fileName", fileName, "err", err)
+		return err
+	}
+	return this.loadJsonData(fileName, fileData)
+}
+
+// json数据的key是导出工具生成的字符串(组合key如"1_2"),加载时使用keyFn重新计算
+func (this *DataKeyMap[K, E]) loadJsonData(fileName string, fileData []byte) error {
+	var jsonMap map[string]E
+	err := unmarshalJsonMap(fileData, &jsonMap)
+	if err != nil {
+		slog.Error("LoadJsonErr", "fileName", fileName, "err", err)
+		return err
+	}
+	cfgMap := make(map[K]E, len(jsonMap))
+	for _, cfg := range jsonMap {
+		this.addCfg(fileName, cfgMap, cfg)
+	}
+	this.cfgs = cfgMap
+	slog.Info("LoadJson", "fileName", fileName, "count", len(this.cfgs))
+	return nil
+}
+
+// 从pb文件加载数据
+func (this *DataKeyMap[K, E]) LoadPb(fileName string) error {
+	fileName, fileData, err := ReadDataFile(fileName)
+	if err != nil {
+		slog.Error("LoadPbErr", "fileName", fileName, "err", err)
+		return err
+	}
+	return this.loadPbData(fileName, fileData)
+}
+
+// 加密的数据先解密
+func (this *DataKeyMap[K, E]) loadPbData(fileName string, fileData []byte) error {
+	fileData, err := DecryptData(fileName, fileData)
+	if err != nil {
+		slog.Error("LoadPbErr", "fileName", fileName, "err", err)
+		return err
+	}
+	reader := bytes.NewReader(fileData)
+	cfgMap := make(map[K]E)
+	for {
+		cfg, newErr := newElement[E]()
+		if newErr != nil {
+			slog.Error("LoadPbErr", "fileName", fileName, "err", newErr)
+			return newErr
+		}
+		err = protodelim.UnmarshalFrom(reader, cfg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			slog.Error("LoadPbErr", "fileName", fileName, "err", err)
+			return err
+		}
+		this.addCfg(fileName, cfgMap, cfg)
+	}
+	this.cfgs = cfgMap
+	slog.Info("LoadPb", "fileName", fileName, "count", len(this.cfgs))
+	return nil
+}
+
+func (this *DataKeyMap[K, E]) addCfg(fileName string, cfgMap map[K]E, cfg E) {
+	key := this.keyFn(cfg)
+	if _, ok := cfgMap[key]; ok {
+		slog.Error("duplicate key", "fileName", fileName, "key", key)
+	}
+	cfgMap[key] = cfg
+}
@@ -55,29 +55,44 @@ type processRegister struct {
 
 // filter:过滤接口,返回false则不加载该文件
 func Load(dataDir string, filter func(fileName string) bool) error {
-    if !atomic.CompareAndSwapInt32(&isLoading, 0, 1) {
-        return ErrLoadingConcurrency
-    }
-    defer atomic.StoreInt32(&isLoading, 0)
     dataDir = filepath.ToSlash(dataDir)
     if strings.LastIndexByte(dataDir, filepath.Separator) != len(dataDir)-1 {
         dataDir += string(filepath.Separator)
     }
+    return LoadFromSource(NewDirSource(dataDir), filter)
+}
+
+// 从打包文件加载
+// filter:过滤接口,返回false则不加载该文件
+func LoadBundle(bundleFile string, filter func(fileName string) bool) error {
+    bundle, err := OpenBundle(bundleFile)
+    if err != nil {
+        return err
+    }
+    return LoadFromSource(bundle, filter)
+}
+
+// filter:过滤接口,返回false则不加载该文件
+func LoadFromSource(source DataSource, filter func(fileName string) bool) error {
+    if !atomic.CompareAndSwapInt32(&isLoading, 0, 1) {
+        return ErrLoadingConcurrency
+    }
+    defer atomic.StoreInt32(&isLoading, 0)
     var err error
     
-    if err = LoadConfig(filter, "ItemCfg.json", dataDir, NewDataMap[*pb.ItemCfg], &ItemCfgs); err != nil {
+    if err = LoadConfigFrom(filter, "ItemCfg.json", source, NewDataMap[*pb.ItemCfg], &ItemCfgs); err != nil {
         return err
     }
-    if err = LoadConfig(filter, "Quests.json", dataDir, NewDataMap[*pb.QuestCfg], &Quests); err != nil {
+    if err = LoadConfigFrom(filter, "Quests.json", source, NewDataMap[*pb.QuestCfg], &Quests); err != nil {
         return err
     }
-    if err = LoadConfig(filter, "levelcfg.json", dataDir, func() *DataSlice[*pb.LevelExp] { return &DataSlice[*pb.LevelExp]{} }, &LevelExps); err != nil {
+    if err = LoadConfigFrom(filter, "levelcfg.json", source, func() *DataSlice[*pb.LevelExp] { return &DataSlice[*pb.LevelExp]{} }, &LevelExps); err != nil {
         return err
     }
-    if err = LoadConfig(filter, "exchange.json", dataDir, NewDataMap[*pb.ExchangeCfg], &ExchangeCfgs); err != nil {
+    if err = LoadConfigFrom(filter, "exchange.json", source, NewDataMap[*pb.ExchangeCfg], &ExchangeCfgs); err != nil {
         return err
     }
-    if err = LoadConfig(filter, "progress_template.json", dataDir, NewDataMap[*pb.ProgressTemplateCfg], &ProgressTemplateCfgs); err != nil {
+    if err = LoadConfigFrom(filter, "progress_template.json", source, NewDataMap[*pb.ProgressTemplateCfg], &ProgressTemplateCfgs); err != nil {
         return err
     }
 
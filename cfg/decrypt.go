@@ -0,0 +1,83 @@
+package cfg
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// 加密数据格式:
+//
+//	magic   [4]byte "XENC"
+//	keyId   [4]byte sha256(key)的前4个字节
+//	nonce   [12]byte
+//	ciphertext (包含16字节的GCM tag),magic和keyId作为附加数据参与校验
+const EncryptMagic = "XENC"
+
+var (
+	ErrDecryptKeyNotSet = errors.New("data is encrypted but decrypt key is not set")
+	ErrDecryptWrongKey  = errors.New("decrypt key does not match")
+	ErrDecryptTampered  = errors.New("encrypted data is corrupted or tampered")
+
+	decryptKey atomic.Pointer[[]byte]
+)
+
+// 设置解密key,长度为16,24,32字节,和导出工具的EncryptKey一致
+// key为nil表示清除
+func SetDecryptKey(key []byte) error {
+	if key == nil {
+		decryptKey.Store(nil)
+		return nil
+	}
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return fmt.Errorf("invalid decrypt key length:%v, must be 16, 24 or 32 bytes", len(key))
+	}
+	keyCopy := append([]byte(nil), key...)
+	decryptKey.Store(&keyCopy)
+	return nil
+}
+
+// 是否是加密的数据
+func IsEncryptedData(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(EncryptMagic))
+}
+
+// 解密pb数据,没有加密的数据直接返回
+func DecryptData(fileName string, data []byte) ([]byte, error) {
+	if !IsEncryptedData(data) {
+		return data, nil
+	}
+	keyPtr := decryptKey.Load()
+	if keyPtr == nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptKeyNotSet, fileName)
+	}
+	key := *keyPtr
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	headerSize := len(EncryptMagic) + 4
+	if len(data) < headerSize+gcm.NonceSize()+gcm.Overhead() {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptTampered, fileName)
+	}
+	keyHash := sha256.Sum256(key)
+	if !bytes.Equal(data[len(EncryptMagic):headerSize], keyHash[:4]) {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptWrongKey, fileName)
+	}
+	nonce := data[headerSize : headerSize+gcm.NonceSize()]
+	plainData, err := gcm.Open(nil, nonce, data[headerSize+gcm.NonceSize():], data[:headerSize])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptTampered, fileName)
+	}
+	return plainData, nil
+}
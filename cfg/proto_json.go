@@ -0,0 +1,143 @@
+package cfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// encoding/json不支持well-known types(如Timestamp Duration Int32Value Struct)和oneof的json格式,
+// 使用了这些类型的配置表用protojson逐个解析配置项,其他的配置表仍然使用encoding/json
+
+var (
+	_protoJsonOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+	_useProtoJsonMap  sync.Map // protoreflect.FullName -> bool
+)
+
+// 解析json格式的map数据,如{"1":{...},"2":{...}}
+func unmarshalJsonMap[K comparable, E any](fileData []byte, cfgMap *map[K]E) error {
+	if !useProtoJson[E]() {
+		return json.Unmarshal(fileData, cfgMap)
+	}
+	var rawMap map[K]json.RawMessage
+	if err := json.Unmarshal(fileData, &rawMap); err != nil {
+		return err
+	}
+	result := make(map[K]E, len(rawMap))
+	for key, raw := range rawMap {
+		cfg, err := unmarshalProtoJson[E](raw)
+		if err != nil {
+			return err
+		}
+		result[key] = cfg
+	}
+	*cfgMap = result
+	return nil
+}
+
+// 解析json格式的数组数据,如[{...},{...}]
+func unmarshalJsonList[E any](fileData []byte, cfgList *[]E) error {
+	if !useProtoJson[E]() {
+		return json.Unmarshal(fileData, cfgList)
+	}
+	var rawList []json.RawMessage
+	if err := json.Unmarshal(fileData, &rawList); err != nil {
+		return err
+	}
+	result := make([]E, 0, len(rawList))
+	for _, raw := range rawList {
+		cfg, err := unmarshalProtoJson[E](raw)
+		if err != nil {
+			return err
+		}
+		result = append(result, cfg)
+	}
+	*cfgList = result
+	return nil
+}
+
+// 解析json格式的分组数据,如{"1":[{...},{...}]}
+func unmarshalJsonGroup[K comparable, E any](fileData []byte, groups *map[K][]E) error {
+	if !useProtoJson[E]() {
+		return json.Unmarshal(fileData, groups)
+	}
+	var rawGroups map[K][]json.RawMessage
+	if err := json.Unmarshal(fileData, &rawGroups); err != nil {
+		return err
+	}
+	result := make(map[K][]E, len(rawGroups))
+	for key, rawList := range rawGroups {
+		group := make([]E, 0, len(rawList))
+		for _, raw := range rawList {
+			cfg, err := unmarshalProtoJson[E](raw)
+			if err != nil {
+				return err
+			}
+			group = append(group, cfg)
+		}
+		result[key] = group
+	}
+	*groups = result
+	return nil
+}
+
+func unmarshalProtoJson[E any](data []byte) (E, error) {
+	cfg, err := newElement[E]()
+	if err != nil {
+		return cfg, err
+	}
+	msg, ok := any(cfg).(proto.Message)
+	if !ok {
+		return cfg, fmt.Errorf("type %T does not implement proto.Message", cfg)
+	}
+	err = _protoJsonOptions.Unmarshal(data, msg)
+	return cfg, err
+}
+
+// 配置项的message(包括子message)中是否有well-known types或者oneof
+func useProtoJson[E any]() bool {
+	cfg, err := newElement[E]()
+	if err != nil {
+		return false
+	}
+	msg, ok := any(cfg).(proto.Message)
+	if !ok {
+		return false
+	}
+	msgDesc := msg.ProtoReflect().Descriptor()
+	if v, ok := _useProtoJsonMap.Load(msgDesc.FullName()); ok {
+		return v.(bool)
+	}
+	use := checkUseProtoJson(msgDesc, make(map[protoreflect.FullName]struct{}))
+	_useProtoJsonMap.Store(msgDesc.FullName(), use)
+	return use
+}
+
+func checkUseProtoJson(msgDesc protoreflect.MessageDescriptor, visited map[protoreflect.FullName]struct{}) bool {
+	if strings.HasPrefix(string(msgDesc.FullName()), "google.protobuf.") {
+		return true
+	}
+	if _, ok := visited[msgDesc.FullName()]; ok {
+		return false
+	}
+	visited[msgDesc.FullName()] = struct{}{}
+	fields := msgDesc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if oneOf := fd.ContainingOneof(); oneOf != nil && !oneOf.IsSynthetic() {
+			return true
+		}
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		if fd.Message() != nil && checkUseProtoJson(fd.Message(), visited) {
+			return true
+		}
+	}
+	return false
+}
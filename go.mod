@@ -5,6 +5,7 @@ go 1.24.0
 require (
 	github.com/fatih/color v1.18.0
 	github.com/jhump/protoreflect v1.17.0
+	github.com/klauspost/compress v1.18.0
 	github.com/xuri/excelize/v2 v2.10.1
 	google.golang.org/protobuf v1.36.10
 	gopkg.in/yaml.v3 v3.0.1
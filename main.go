@@ -4,15 +4,30 @@ import (
 	"excelexporter/tool"
 	"flag"
 	"fmt"
+	"os"
+	"time"
 )
 
 func main() {
 	var configFile string
+	var force bool
+	var watch bool
 	flag.StringVar(&configFile, "config", "exporter.yaml", "config file")
+	flag.BoolVar(&force, "force", false, "ignore cache and export all sheets")
+	flag.BoolVar(&watch, "watch", false, "watch excel and proto files, export automatically when changed")
 	flag.Parse()
-	err := tool.ExportByConfig(configFile)
+	options, err := tool.LoadExportOption(configFile)
+	if err == nil {
+		options.Force = force
+		if watch {
+			err = tool.WatchAndExport(options, time.Second, nil)
+		} else {
+			err = tool.ExportByOption(options)
+		}
+	}
 	if err != nil {
 		fmt.Println(fmt.Sprintf("err:%v", err))
+		os.Exit(1)
 	} else {
 		fmt.Println("Export Success")
 	}
@@ -0,0 +1,51 @@
+package tool
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// 打包文件格式,和cfg.Bundle一致
+const (
+	bundleMagic   = "XCFG"
+	bundleVersion = 1
+)
+
+// 打包文件中的一个配置表
+type bundleTable struct {
+	Name string // 导出的文件名,如Quests.pb
+	Data []byte
+}
+
+// 把所有配置表打包成一个文件
+// 文件头: magic,version,配置表数量,配置表目录(name,offset,length,md5),然后是所有配置表的数据
+func marshalBundle(tables []*bundleTable) ([]byte, error) {
+	headerSize := len(bundleMagic) + 4 + 4
+	for _, table := range tables {
+		if len(table.Name) > math.MaxUint16 {
+			return nil, fmt.Errorf("bundle table name too long:%v", table.Name)
+		}
+		headerSize += 2 + len(table.Name) + 8 + 8 + md5.Size
+	}
+	buffer := bytes.NewBuffer(make([]byte, 0, headerSize))
+	buffer.WriteString(bundleMagic)
+	_ = binary.Write(buffer, binary.LittleEndian, uint32(bundleVersion))
+	_ = binary.Write(buffer, binary.LittleEndian, uint32(len(tables)))
+	offset := uint64(headerSize)
+	for _, table := range tables {
+		_ = binary.Write(buffer, binary.LittleEndian, uint16(len(table.Name)))
+		buffer.WriteString(table.Name)
+		_ = binary.Write(buffer, binary.LittleEndian, offset)
+		_ = binary.Write(buffer, binary.LittleEndian, uint64(len(table.Data)))
+		hash := md5.Sum(table.Data)
+		buffer.Write(hash[:])
+		offset += uint64(len(table.Data))
+	}
+	for _, table := range tables {
+		buffer.Write(table.Data)
+	}
+	return buffer.Bytes(), nil
+}
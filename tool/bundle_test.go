@@ -0,0 +1,82 @@
+package tool
+
+import (
+	"errors"
+	"testing"
+
+	"excelexporter/cfg"
+	"excelexporter/example/pb"
+)
+
+func TestMarshalBundle(t *testing.T) {
+	initProtoForTest(t)
+	mapData := map[int32]any{
+		1: map[string]any{"CfgId": int32(1), "Name": "A"},
+		2: map[string]any{"CfgId": int32(2), "Name": "B"},
+	}
+	mapPbData, err := marshalToProtoBinary(mapData, &SheetOption{MessageName: "QuestCfg", MgrType: "map"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectPbData, err := marshalToProtoBinary(map[string]any{"CfgId": int32(3), "Name": "C"}, &SheetOption{MessageName: "QuestCfg", MgrType: "object"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundleData, err := marshalBundle([]*bundleTable{
+		{Name: "Quests.pb", Data: mapPbData},
+		{Name: "Quest.pb", Data: objectPbData},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 用cfg的加载接口读取打包文件
+	bundle, err := cfg.ParseBundle(bundleData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Tables()) != 2 || bundle.Tables()[0].Name != "Quests.pb" || bundle.Tables()[1].Name != "Quest.pb" {
+		t.Fatalf("unexpected tables:%v", bundle.Tables())
+	}
+	var quests *cfg.DataMap[*pb.QuestCfg]
+	if err = cfg.LoadConfigFrom(nil, "Quests.json", bundle, cfg.NewDataMap[*pb.QuestCfg], &quests); err != nil {
+		t.Fatal(err)
+	}
+	if quests.GetCfg(1).GetName() != "A" || quests.GetCfg(2).GetName() != "B" {
+		t.Errorf("unexpected quests")
+	}
+	var quest *pb.QuestCfg
+	if err = cfg.LoadObjectConfigFrom(nil, "Quest.json", bundle, func() *pb.QuestCfg { return &pb.QuestCfg{} }, &quest); err != nil {
+		t.Fatal(err)
+	}
+	if quest.GetCfgId() != 3 || quest.GetName() != "C" {
+		t.Errorf("unexpected quest:%v", quest)
+	}
+	if _, err = bundle.ReadTable("None.pb"); !errors.Is(err, cfg.ErrBundleTableNotFound) {
+		t.Errorf("expected ErrBundleTableNotFound, got %v", err)
+	}
+
+	// 数据被修改
+	tamperedData := append([]byte(nil), bundleData...)
+	tamperedData[len(tamperedData)-1] ^= 0xff
+	tampered, err := cfg.ParseBundle(tamperedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tampered.ReadTable("Quest.pb"); !errors.Is(err, cfg.ErrBundleTableHash) {
+		t.Errorf("expected ErrBundleTableHash, got %v", err)
+	}
+	if _, err = tampered.ReadTable("Quests.pb"); err != nil {
+		t.Errorf("unexpected err:%v", err)
+	}
+	// 文件头错误
+	if _, err = cfg.ParseBundle([]byte("ABCD")); !errors.Is(err, cfg.ErrBundleFormat) {
+		t.Errorf("expected ErrBundleFormat, got %v", err)
+	}
+	if _, err = cfg.ParseBundle(bundleData[:20]); !errors.Is(err, cfg.ErrBundleFormat) {
+		t.Errorf("expected ErrBundleFormat, got %v", err)
+	}
+	if _, err = cfg.ParseBundle(bundleData[:len(bundleData)-1]); !errors.Is(err, cfg.ErrBundleFormat) {
+		t.Errorf("expected ErrBundleFormat, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const bytesTestProto = `syntax = "proto3";
+package bytestest;
+
+import "google/protobuf/wrappers.proto";
+
+message BytesCfg {
+  int32 CfgId = 1;
+  bytes Raw = 2;
+  bytes B64 = 3;
+  bytes Hex = 4;
+  repeated bytes List = 5;
+  google.protobuf.BytesValue Opt = 6;
+}
+`
+
+func TestParseBytesCell(t *testing.T) {
+	tests := []struct {
+		cellValue string
+		format    string
+		want      []byte
+		wantErr   bool
+	}{
+		{"abc", "", []byte("abc"), false},
+		{"中文", "", []byte("中文"), false},
+		{"3q2+7w==", "base64", []byte{0xde, 0xad, 0xbe, 0xef}, false},
+		{"3q2+7w", "Base64", []byte{0xde, 0xad, 0xbe, 0xef}, false},
+		{"deadbeef", "hex", []byte{0xde, 0xad, 0xbe, 0xef}, false},
+		{"0xDE AD BE EF", "hex", []byte{0xde, 0xad, 0xbe, 0xef}, false},
+		{"xyz", "hex", nil, true},
+		{"!!", "base64", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBytesCell(tt.cellValue, tt.format)
+		if (err != nil) != tt.wantErr || !bytes.Equal(got, tt.want) {
+			t.Errorf("ParseBytesCell(%q,%q)=%v,%v want:%v", tt.cellValue, tt.format, got, err, tt.want)
+		}
+	}
+}
+
+func TestConvertSheetBytes(t *testing.T) {
+	initTempProtoForTest(t, "bytes_test.proto", bytesTestProto)
+	f := newTestSheetFile(t, "Bytes",
+		[]interface{}{"CfgId", "Raw", "B64#Format=base64", "Hex#Format=hex", "List#Format=hex", "Opt#Format=base64"},
+		[]interface{}{"1", "hello", "3q2+7w==", "0xdeadbeef", "01;0203", "AA=="},
+		[]interface{}{"2", "", "", "zz"},
+	)
+	defer func() { _ = f.Close() }()
+	diags := NewDiagnostics()
+	opt := &SheetOption{SheetName: "Bytes", MessageName: "BytesCfg", MgrType: "slice"}
+	data, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), opt, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bytes字段导出为base64
+	want := `[{"B64":"3q2+7w==","CfgId":1,"Hex":"3q2+7w==","List":["AQ==","AgM="],"Opt":"AA==","Raw":"aGVsbG8="},{"CfgId":2}]`
+	if string(jsonData) != want {
+		t.Fatalf("got:\n%v\nwant:\n%v", string(jsonData), want)
+	}
+	if len(diags.Items()) != 1 || diags.Items()[0].Cell != "D3" {
+		t.Fatalf("unexpected diags: %v", diags.Items())
+	}
+
+	// pb格式解析回来的bytes和单元格的值一致
+	pbData, err := marshalToProtoBinary(data, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgType := FindMessageDescriptor("BytesCfg").UnwrapMessage()
+	msg := dynamicpb.NewMessage(msgType)
+	if err = protodelim.UnmarshalFrom(bytes.NewReader(pbData), msg); err != nil {
+		t.Fatal(err)
+	}
+	fields := msgType.Fields()
+	if got := msg.Get(fields.ByName("Raw")).Bytes(); string(got) != "hello" {
+		t.Fatalf("Raw got:%v", got)
+	}
+	if got := msg.Get(fields.ByName("Hex")).Bytes(); !bytes.Equal(got, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Fatalf("Hex got:%v", got)
+	}
+	list := msg.Get(fields.ByName("List")).List()
+	var gotList [][]byte
+	for i := 0; i < list.Len(); i++ {
+		gotList = append(gotList, list.Get(i).Bytes())
+	}
+	if !reflect.DeepEqual(gotList, [][]byte{{0x01}, {0x02, 0x03}}) {
+		t.Fatalf("List got:%v", gotList)
+	}
+}
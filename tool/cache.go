@@ -0,0 +1,198 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// 导出工具的版本号,转换逻辑有变化时需要修改,使之前的导出缓存失效
+const ToolVersion = "1.13.7"
+
+func init() {
+	// 转换后的数据都是interface,gob需要注册具体类型
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+	gob.Register(map[int]any{})
+	gob.Register(map[int8]any{})
+	gob.Register(map[int16]any{})
+	gob.Register(map[int32]any{})
+	gob.Register(map[int64]any{})
+	gob.Register(map[uint]any{})
+	gob.Register(map[uint8]any{})
+	gob.Register(map[uint16]any{})
+	gob.Register(map[uint32]any{})
+	gob.Register(map[uint64]any{})
+}
+
+// 增量导出的缓存
+// 源文件和schema都没有变化的sheet,直接使用上次转换的数据
+type exportCache struct {
+	SchemaHash string                 // 工具版本,导出设置,proto文件的hash
+	Sheets     map[string]*sheetCache // key:sheetCacheKey
+}
+
+// 一个sheet的转换结果缓存
+type sheetCache struct {
+	ExcelHash    string // excel文件的hash
+	Data         any
+	ResultOption *SheetOption
+	Diagnostics  []*Diagnostic
+}
+
+func sheetCacheKey(task *convertTask) string {
+	opt := task.SheetOption
+	return strings.Join([]string{task.ExcelFileName, opt.SheetName, opt.MessageName, opt.MgrType, opt.MapKeyName, opt.Layout}, "|")
+}
+
+// 计算工具版本,导出设置,proto文件的hash,任意一个变化,所有的缓存都失效
+func computeSchemaHash(exportOption *ExportOption) (string, error) {
+	// 不影响转换结果的设置项不参与计算
+	opt := *exportOption
+	opt.Concurrency = 0
+	opt.CacheFile = ""
+	opt.EncryptKey = ""
+	optionData, err := yaml.Marshal(&opt)
+	if err != nil {
+		return "", err
+	}
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString(ToolVersion)
+	buffer.Write(optionData)
+	// 已经解析的proto文件以及import的proto文件都参与计算
+	var marshalErr error
+	rangeProtoFiles(func(fd *desc.FileDescriptor) {
+		if marshalErr != nil {
+			return
+		}
+		protoData, err := proto.MarshalOptions{Deterministic: true}.Marshal(fd.AsFileDescriptorProto())
+		if err != nil {
+			marshalErr = err
+			return
+		}
+		buffer.WriteString(fd.GetName())
+		buffer.Write(protoData)
+	})
+	if marshalErr != nil {
+		return "", marshalErr
+	}
+	return GetMd5(buffer.Bytes()), nil
+}
+
+// 计算源文件的hash,hashes缓存已经计算过的文件
+func getFileHash(hashes map[string]string, fileName string) (string, error) {
+	if v, ok := hashes[fileName]; ok {
+		return v, nil
+	}
+	fileData, err := os.ReadFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	v := GetMd5(fileData)
+	hashes[fileName] = v
+	return v, nil
+}
+
+// 是否使用增量导出的缓存,watch模式下没有设置CacheFile时使用内存缓存
+func (opt *ExportOption) useExportCache() bool {
+	return opt.CacheFile != "" || opt.watching
+}
+
+// 加载缓存,缓存文件不存在或者schema有变化时,返回空的缓存
+func loadExportCache(exportOption *ExportOption, schemaHash string) *exportCache {
+	cache := &exportCache{
+		SchemaHash: schemaHash,
+		Sheets:     make(map[string]*sheetCache),
+	}
+	if exportOption.Force {
+		return cache
+	}
+	cacheName := "memory"
+	fileData := exportOption.watchCacheData
+	if exportOption.CacheFile != "" {
+		cacheName = exportOption.CacheFile
+		var err error
+		if fileData, err = os.ReadFile(cacheName); err != nil {
+			if !os.IsNotExist(err) {
+				color.Yellow("read cache err:%v file:%v", err, cacheName)
+			}
+			return cache
+		}
+	}
+	if fileData == nil {
+		return cache
+	}
+	oldCache := &exportCache{}
+	if err := gob.NewDecoder(bytes.NewReader(fileData)).Decode(oldCache); err != nil {
+		color.Yellow("decode cache err:%v file:%v", err, cacheName)
+		return cache
+	}
+	if oldCache.SchemaHash != schemaHash || oldCache.Sheets == nil {
+		fmt.Println(fmt.Sprintf("schema changed, ignore cache:%v", cacheName))
+		return cache
+	}
+	return oldCache
+}
+
+// 使用缓存的转换结果,返回是否命中
+func (c *exportCache) apply(task *convertTask, excelHash string) bool {
+	sc, ok := c.Sheets[sheetCacheKey(task)]
+	if !ok || sc.ExcelHash != excelHash || sc.ResultOption == nil {
+		return false
+	}
+	task.Data = sc.Data
+	task.ResultOption = sc.ResultOption
+	task.Diags = NewDiagnostics()
+	for _, diag := range sc.Diagnostics {
+		task.Diags.Add(diag)
+	}
+	task.Cached = true
+	return true
+}
+
+// 保存所有任务的转换结果
+// NOTE:需要在合并数据之前调用,因为合并会修改Data
+func saveExportCache(exportOption *ExportOption, schemaHash string, tasks []*convertTask) error {
+	cache := &exportCache{
+		SchemaHash: schemaHash,
+		Sheets:     make(map[string]*sheetCache),
+	}
+	for _, task := range tasks {
+		if task.Err != nil || task.ExcelHash == "" {
+			continue
+		}
+		cache.Sheets[sheetCacheKey(task)] = &sheetCache{
+			ExcelHash:    task.ExcelHash,
+			Data:         task.Data,
+			ResultOption: task.ResultOption,
+			Diagnostics:  task.Diags.Items(),
+		}
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := gob.NewEncoder(buffer).Encode(cache); err != nil {
+		return err
+	}
+	if exportOption.CacheFile == "" {
+		// 内存缓存也保存编码后的数据,合并数据时不会修改缓存
+		exportOption.watchCacheData = buffer.Bytes()
+		return nil
+	}
+	return os.WriteFile(exportOption.CacheFile, buffer.Bytes(), os.ModePerm)
+}
+
+// 写入导出的文件,skipUnchanged为true时(watch模式)内容没有变化的文件不再写入
+func writeOutputFile(fileName string, data []byte, skipUnchanged bool) error {
+	if skipUnchanged {
+		if oldData, err := os.ReadFile(fileName); err == nil && bytes.Equal(oldData, data) {
+			return nil
+		}
+	}
+	return os.WriteFile(fileName, data, os.ModePerm)
+}
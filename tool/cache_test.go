@@ -0,0 +1,147 @@
+package tool
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExportCache(t *testing.T) {
+	exportOption := &ExportOption{
+		CacheFile: filepath.Join(t.TempDir(), "exporter.cache"),
+	}
+	newTask := func() *convertTask {
+		return &convertTask{
+			ExcelFileName: "questcfg.xlsx",
+			SheetOption: &SheetOption{
+				SheetName:   "QuestCfg",
+				MessageName: "QuestCfg",
+				MgrType:     "map",
+			},
+		}
+	}
+	data := map[int32]any{
+		1: map[string]any{
+			"CfgId":   int32(1),
+			"Name":    "quest1",
+			"Rewards": []any{map[string]any{"CfgId": int32(1), "Num": int32(10)}},
+			"Properties": map[string]any{
+				"k": "v",
+			},
+		},
+	}
+	task := newTask()
+	task.ExcelHash = "hash1"
+	task.Data = data
+	task.ResultOption = &SheetOption{SheetName: "QuestCfg", MapKeyName: "CfgId", MapKeyType: "int32"}
+	task.Diags = NewDiagnostics()
+	task.Diags.Warnf("questcfg.xlsx", "QuestCfg", "test warning")
+	if err := saveExportCache(exportOption, "schema1", []*convertTask{task}); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := loadExportCache(exportOption, "schema1")
+	cachedTask := newTask()
+	if !cache.apply(cachedTask, "hash1") {
+		t.Fatal("expected cache hit")
+	}
+	if !reflect.DeepEqual(cachedTask.Data, data) {
+		t.Errorf("cached data mismatch: %v", cachedTask.Data)
+	}
+	if cachedTask.ResultOption.MapKeyType != "int32" || cachedTask.Diags.Count(SeverityWarning) != 1 {
+		t.Errorf("unexpected cached result: %+v %v", cachedTask.ResultOption, cachedTask.Diags.Items())
+	}
+	if cache.apply(newTask(), "hash2") {
+		t.Error("excel changed, expected cache miss")
+	}
+	if loadExportCache(exportOption, "schema2").apply(newTask(), "hash1") {
+		t.Error("schema changed, expected cache miss")
+	}
+	exportOption.Force = true
+	if loadExportCache(exportOption, "schema1").apply(newTask(), "hash1") {
+		t.Error("force, expected cache miss")
+	}
+}
+
+// import的proto文件有变化时,schema hash也要变化,缓存失效后重新转换
+func TestSchemaHashImportedProto(t *testing.T) {
+	_protoDescLock.Lock()
+	oldProtoDesc := _protoDesc
+	_protoDesc = nil
+	_protoDescLock.Unlock()
+	defer func() {
+		ResetProtoFile()
+		_protoDescLock.Lock()
+		_protoDesc = oldProtoDesc
+		_protoDescLock.Unlock()
+	}()
+	dir := t.TempDir()
+	writeProto := func(fileName, src string) {
+		if err := os.WriteFile(filepath.Join(dir, fileName), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exportOption := &ExportOption{
+		CacheFile:  filepath.Join(t.TempDir(), "exporter.cache"),
+		ProtoPath:  dir,
+		ProtoFiles: []string{"cachecfg.proto"},
+	}
+	schemaHash := func() string {
+		ResetProtoFile()
+		if err := ParseProtoFile([]string{dir}, exportOption.ProtoFiles...); err != nil {
+			t.Fatal(err)
+		}
+		v, err := computeSchemaHash(exportOption)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	writeProto("cachecfg.proto", `syntax = "proto3";
+import "cachedep.proto";
+import "google/protobuf/timestamp.proto";
+message CacheCfg {
+  int32 CfgId = 1;
+  CacheDep Dep = 2;
+  google.protobuf.Timestamp Time = 3;
+}
+`)
+	writeProto("cachedep.proto", `syntax = "proto3";
+message CacheDep {
+  int32 Num = 1;
+}
+`)
+	oldHash := schemaHash()
+	if schemaHash() != oldHash {
+		t.Fatal("schema hash not stable")
+	}
+	task := &convertTask{
+		ExcelFileName: "cachecfg.xlsx",
+		SheetOption:   &SheetOption{SheetName: "CacheCfg", MessageName: "CacheCfg", MgrType: "map"},
+		ExcelHash:     "hash1",
+		Data:          map[int32]any{1: map[string]any{"CfgId": int32(1)}},
+		ResultOption:  &SheetOption{SheetName: "CacheCfg"},
+		Diags:         NewDiagnostics(),
+	}
+	if err := saveExportCache(exportOption, oldHash, []*convertTask{task}); err != nil {
+		t.Fatal(err)
+	}
+	// 只修改import的proto文件
+	writeProto("cachedep.proto", `syntax = "proto3";
+message CacheDep {
+  int64 Num = 1;
+}
+`)
+	newHash := schemaHash()
+	if newHash == oldHash {
+		t.Fatal("imported proto changed, expected schema hash changed")
+	}
+	newTask := &convertTask{
+		ExcelFileName: "cachecfg.xlsx",
+		SheetOption:   &SheetOption{SheetName: "CacheCfg", MessageName: "CacheCfg", MgrType: "map"},
+	}
+	if loadExportCache(exportOption, newHash).apply(newTask, "hash1") {
+		t.Error("imported proto changed, expected cache miss")
+	}
+}
@@ -0,0 +1,141 @@
+package tool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// excel单元格按原始值读取,不使用单元格的显示格式
+// 如日期显示为2/19/24,百分比显示为50%,大的数字显示为1.23E+06,读取的都是原始的数字
+// 日期格式的单元格转换成2024-02-19 10:00:00格式的字符串,整数字段再按#Format转换成时间戳或者日期
+
+// excel内置的日期时间格式:14-22 45-47,中日韩语言的日期格式:27-36 50-58
+func isDateBuiltInNumFmt(numFmt int) bool {
+	return (numFmt >= 14 && numFmt <= 22) || (numFmt >= 27 && numFmt <= 36) ||
+		(numFmt >= 45 && numFmt <= 47) || (numFmt >= 50 && numFmt <= 58)
+}
+
+// 自定义格式是否是日期时间格式,如yyyy-mm-dd hh:mm:ss,去掉引号中的文本,转义字符和[Red]等颜色标记后包含ymdhs
+func isDateCustomNumFmt(fmtCode string) bool {
+	inQuote := false
+	inBracket := false
+	for i := 0; i < len(fmtCode); i++ {
+		c := fmtCode[i]
+		switch {
+		case inQuote:
+			inQuote = c != '"'
+		case inBracket:
+			inBracket = c != ']'
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			inBracket = true
+		case c == '\\' || c == '_' || c == '*':
+			i++ // 转义字符和占位字符后面的字符不是格式
+		default:
+			switch c {
+			case 'y', 'Y', 'm', 'M', 'd', 'D', 'h', 'H', 's', 'S':
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 日期时间格式的单元格的值(excel中的日期是1900-01-01开始的天数)转换成字符串
+// 没有时间的转换成2024-02-19,只有时间的转换成10:00:00
+func formatExcelDate(serial float64, date1904 bool) (string, bool) {
+	t, err := excelize.ExcelDateToTime(serial, date1904)
+	if err != nil {
+		return "", false
+	}
+	t = t.Round(time.Second)
+	if serial < 1 {
+		return t.Format("15:04:05"), true
+	}
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02"), true
+	}
+	return t.Format("2006-01-02 15:04:05"), true
+}
+
+// 转换单元格的原始值:bool单元格转换成TRUE FALSE,日期时间格式的数字转换成日期字符串
+func (r *excelRows) convertCellValue(cellName, value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return value
+	}
+	cellType, err := r.file.GetCellType(r.sheetName, cellName)
+	if err != nil {
+		return value
+	}
+	switch cellType {
+	case excelize.CellTypeBool:
+		if value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case excelize.CellTypeUnset, excelize.CellTypeNumber:
+		styleID, err := r.file.GetCellStyle(r.sheetName, cellName)
+		if err != nil || styleID == 0 || !r.isDateStyle(styleID) {
+			return value
+		}
+		serial, _ := strconv.ParseFloat(value, 64)
+		if dateValue, ok := formatExcelDate(serial, r.date1904); ok {
+			return dateValue
+		}
+	}
+	return value
+}
+
+// 单元格的样式是否是日期时间格式,结果按样式id缓存
+func (r *excelRows) isDateStyle(styleID int) bool {
+	if isDate, ok := r.dateStyles[styleID]; ok {
+		return isDate
+	}
+	isDate := false
+	if style, err := r.file.GetStyle(styleID); err == nil && style != nil {
+		if style.CustomNumFmt != nil {
+			isDate = isDateCustomNumFmt(*style.CustomNumFmt)
+		} else {
+			isDate = isDateBuiltInNumFmt(style.NumFmt)
+		}
+	}
+	r.dateStyles[styleID] = isDate
+	return isDate
+}
+
+// 计算没有缓存值的公式单元格(如程序生成的xlsx文件),需要打开ExportOption.CalcFormula
+func (r *excelRows) calcFormulaCell(cellName string) (string, error) {
+	formula, err := r.file.GetCellFormula(r.sheetName, cellName)
+	if err != nil || formula == "" {
+		return "", err
+	}
+	return r.file.CalcCellValue(r.sheetName, cellName, excelize.Options{RawCellValue: true})
+}
+
+// 整数字段填日期时间时(如2024-02-19 10:00:00),需要列指定时间类型,不会默认转换:
+// #Format=Date转换成20240219格式(TimeType_Date),#Format=Timestamp转换成时间戳(TimeType_Timestamp)
+// 不是日期时间或者没有指定时间类型时返回parseErr
+func parseTimeIntCell(ctx *CellContext, columnOption *ColumnOption, cellValue string, parseErr error) (int64, error) {
+	if IsDigit(cellValue) {
+		return 0, parseErr
+	}
+	t, err := parseTimestampCell(cellValue, ctx.getLocation())
+	if err != nil {
+		return 0, parseErr
+	}
+	if columnOption != nil {
+		switch strings.ToLower(columnOption.Format) {
+		case "date":
+			t = t.In(ctx.getLocation())
+			return int64(t.Year()*10000 + int(t.Month())*100 + t.Day()), nil
+		case "timestamp":
+			return t.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("%w, date time needs #Format=Date or #Format=Timestamp", parseErr)
+}
@@ -0,0 +1,147 @@
+package tool
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const cellValueTestProto = `syntax = "proto3";
+package cellvaluetest;
+
+message CellValueCfg {
+  int32 CfgId = 1;
+  int32 BeginTime = 2;
+  int32 BeginDate = 3;
+  float Rate = 4;
+  int64 Big = 5;
+  bool Open = 6;
+  string Text = 7;
+  int32 Sum = 8;
+  uint32 EndTime = 9;
+}
+`
+
+func TestIsDateCustomNumFmt(t *testing.T) {
+	tests := map[string]bool{
+		"yyyy/mm/dd":          true,
+		"h:mm":                true,
+		`yyyy"年"m"月"d"日"`:     true,
+		"[$-409]mmm d, yyyy":  true,
+		`0.00"d"`:             false,
+		"[Red]0.00":           false,
+		"0%":                  false,
+		"#,##0_);(#,##0)":     false,
+		"General":             false,
+		`0.00\s`:              false,
+		"0.00E+00":            false,
+		"@":                   false,
+		"[h]:mm:ss;@":         true,
+		`#,##0.00_ "元";[Red]`: false,
+	}
+	for fmtCode, want := range tests {
+		if got := isDateCustomNumFmt(fmtCode); got != want {
+			t.Errorf("isDateCustomNumFmt(%q)=%v want:%v", fmtCode, got, want)
+		}
+	}
+}
+
+func TestConvertSheetCellValue(t *testing.T) {
+	initTempProtoForTest(t, "cell_value_test.proto", cellValueTestProto)
+	f := newTestSheetFile(t, "CellValue",
+		[]interface{}{"CfgId", "BeginTime#Format=Timestamp", "BeginDate#Format=Date", "Rate", "Big", "Open", "Text", "Sum"},
+		[]interface{}{"1"},
+		[]interface{}{"2", "2024-02-19 10:00:00", "2024/02/19", "0.25", "100", "0", "abc"},
+	)
+	defer func() { _ = f.Close() }()
+	// 日期,自定义日期格式,百分比,科学计数法,bool,公式
+	setCell := func(cell string, value any, style *excelize.Style) {
+		if err := f.SetCellValue("CellValue", cell, value); err != nil {
+			t.Fatal(err)
+		}
+		if style == nil {
+			return
+		}
+		styleID, err := f.NewStyle(style)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = f.SetCellStyle("CellValue", cell, cell, styleID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dateFmt := "yyyy/mm/dd"
+	setCell("B2", time.Date(2024, 2, 19, 10, 0, 0, 0, time.UTC), nil)
+	setCell("C2", 45341, &excelize.Style{CustomNumFmt: &dateFmt})
+	setCell("D2", 0.5, &excelize.Style{NumFmt: 9})
+	setCell("E2", 1230000, &excelize.Style{NumFmt: 11})
+	setCell("F2", true, nil)
+	setCell("G2", 45341, &excelize.Style{NumFmt: 14})
+	if err := f.SetCellFormula("CellValue", "H2", "A2+10"); err != nil {
+		t.Fatal(err)
+	}
+
+	opt := &SheetOption{SheetName: "CellValue", MessageName: "CellValueCfg", MgrType: "slice"}
+	convert := func(calcFormula bool) string {
+		diags := NewDiagnostics()
+		data, err := ConvertSourceSheet(&ExportOption{TimeZone: "Asia/Shanghai", CalcFormula: calcFormula}, NewExcelSource(f), opt, diags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(diags.Items()) > 0 {
+			t.Fatalf("unexpected diags: %v", diags.Items())
+		}
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(jsonData)
+	}
+	want := `[{"BeginDate":20240219,"BeginTime":1708308000,"Big":1230000,"CfgId":1,"Open":true,"Rate":0.5,"Text":"2024-02-19"},` +
+		`{"BeginDate":20240219,"BeginTime":1708308000,"Big":100,"CfgId":2,"Open":false,"Rate":0.25,"Text":"abc"}]`
+	if got := convert(false); got != want {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+	// 没有缓存值的公式单元格,打开CalcFormula时计算
+	want = `[{"BeginDate":20240219,"BeginTime":1708308000,"Big":1230000,"CfgId":1,"Open":true,"Rate":0.5,"Sum":11,"Text":"2024-02-19"},` +
+		`{"BeginDate":20240219,"BeginTime":1708308000,"Big":100,"CfgId":2,"Open":false,"Rate":0.25,"Text":"abc"}]`
+	if got := convert(true); got != want {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestConvertTimeIntCell(t *testing.T) {
+	initTempProtoForTest(t, "cell_value_test.proto", cellValueTestProto)
+	msgDesc := FindMessageDescriptor("CellValueCfg")
+	tests := []struct {
+		field    string
+		format   string
+		value    string
+		want     any
+		wantDiag string
+	}{
+		// 没有指定时间类型时不转换
+		{"BeginTime", "", "2024-02-19", int32(0), "WARN field:BeginTime invalid int32 value \"2024-02-19\", date time needs #Format=Date or #Format=Timestamp"},
+		{"BeginTime", "Date", "2024-02-19", int32(20240219), ""},
+		{"BeginTime", "Timestamp", "2024-02-19", int32(1708300800), ""},
+		{"Big", "Timestamp", "2024-02-19", int64(1708300800), ""},
+		// 超出字段类型的范围
+		{"BeginTime", "Timestamp", "2040-01-01", nil, "ERROR field:BeginTime time value 2208988800 out of int32 range"},
+		{"EndTime", "Timestamp", "2040-01-01", uint32(2208988800), ""},
+		{"EndTime", "Timestamp", "1960-01-01", nil, "ERROR field:EndTime time value -315619200 out of uint32 range"},
+	}
+	for _, tt := range tests {
+		diags := NewDiagnostics()
+		ctx := &CellContext{Diags: diags, Location: time.UTC}
+		got := convertFieldValue(ctx, msgDesc.FindFieldByName(tt.field), &ColumnOption{Name: tt.field, Format: tt.format}, tt.value)
+		var gotDiag string
+		if items := diags.Items(); len(items) > 0 {
+			gotDiag = items[0].Severity.String() + " " + items[0].Message
+		}
+		if got != tt.want || gotDiag != tt.wantDiag {
+			t.Errorf("%v#Format=%v %v got:%v %q want:%v %q", tt.field, tt.format, tt.value, got, gotDiag, tt.want, tt.wantDiag)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package tool
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 列名路径中的一段,如Rewards[0].CfgId中的Rewards[0]和CfgId
+type columnPathSegment struct {
+	Name  string
+	Index int // repeated字段的下标,没有下标时为-1
+}
+
+// 解析列名路径,如Base.Pos.X,Rewards[0].CfgId,NextQuests[1]
+// 每一段最多只能有一个下标,格式错误时返回false
+func parseColumnPath(name string) ([]columnPathSegment, bool) {
+	var segments []columnPathSegment
+	for _, s := range strings.Split(name, ".") {
+		segment := columnPathSegment{Name: s, Index: -1}
+		if idx := strings.IndexByte(s, '['); idx >= 0 {
+			if idx == 0 || !strings.HasSuffix(s, "]") {
+				return nil, false
+			}
+			index, err := strconv.Atoi(s[idx+1 : len(s)-1])
+			if err != nil || index < 0 {
+				return nil, false
+			}
+			segment.Name = s[:idx]
+			segment.Index = index
+		}
+		if segment.Name == "" || strings.ContainsAny(segment.Name, "[]") {
+			return nil, false
+		}
+		segments = append(segments, segment)
+	}
+	return segments, true
+}
+
+// 列名中是否有下标
+func hasColumnIndex(name string) bool {
+	if !strings.Contains(name, "[") {
+		return false
+	}
+	segments, ok := parseColumnPath(name)
+	if !ok {
+		return false
+	}
+	for _, segment := range segments {
+		if segment.Index >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// 去掉列名中的下标,如Rewards[0].CfgId转换成Rewards.CfgId
+func stripColumnIndex(name string) string {
+	if !hasColumnIndex(name) {
+		return name
+	}
+	segments, _ := parseColumnPath(name)
+	names := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		names = append(names, segment.Name)
+	}
+	return strings.Join(names, ".")
+}
+
+// 合并带下标的列时,先按下标记录数组的元素,合并完再转换成数组
+type indexedList map[int]any
+
+// 按列名路径把值设置到rowValue中,中间的子对象不存在时创建
+func setColumnPathValue(m map[string]any, segments []columnPathSegment, value any) {
+	for i, segment := range segments {
+		isLast := i == len(segments)-1
+		if segment.Index < 0 {
+			if isLast {
+				m[segment.Name] = value
+				return
+			}
+			childValue, ok := m[segment.Name].(map[string]any)
+			if !ok {
+				childValue = make(map[string]any)
+				m[segment.Name] = childValue
+			}
+			m = childValue
+			continue
+		}
+		list := getIndexedList(m, segment.Name)
+		if isLast {
+			list[segment.Index] = value
+			return
+		}
+		childValue, ok := list[segment.Index].(map[string]any)
+		if !ok {
+			childValue = make(map[string]any)
+			list[segment.Index] = childValue
+		}
+		m = childValue
+	}
+}
+
+// 获取带下标的字段的元素,已经有值的数组(如同时配置了Rewards列)保留原有的元素
+func getIndexedList(m map[string]any, name string) indexedList {
+	switch v := m[name].(type) {
+	case indexedList:
+		return v
+	case []any:
+		list := make(indexedList, len(v))
+		for i, elem := range v {
+			list[i] = elem
+		}
+		m[name] = list
+		return list
+	}
+	list := make(indexedList)
+	m[name] = list
+	return list
+}
+
+// 把合并过程中的indexedList转换成数组,按下标排序,没有配置的下标(所有列都为空)直接跳过
+func finishIndexedList(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, child := range v {
+			v[k] = finishIndexedList(child)
+		}
+		return v
+	case indexedList:
+		indexes := make([]int, 0, len(v))
+		for index := range v {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		list := make([]any, 0, len(indexes))
+		for _, index := range indexes {
+			list = append(list, finishIndexedList(v[index]))
+		}
+		return list
+	}
+	return value
+}
+
+// 把列名路径转换成列名,如Rewards[0].CfgId
+func formatColumnPath(segments []columnPathSegment) string {
+	names := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment.Index >= 0 {
+			names = append(names, segment.Name+"["+strconv.Itoa(segment.Index)+"]")
+		} else {
+			names = append(names, segment.Name)
+		}
+	}
+	return strings.Join(names, ".")
+}
@@ -0,0 +1,81 @@
+package tool
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 导出格式对应的压缩方式,format是ExportFormats中的格式名,如pb
+func (opt *ExportOption) GetCompress(format string) string {
+	for k, v := range opt.Compress {
+		if strings.EqualFold(strings.TrimSpace(k), format) {
+			return strings.ToLower(strings.TrimSpace(v))
+		}
+	}
+	return ""
+}
+
+// 检查压缩设置,只能配置ExportFormats中的导出格式
+func checkCompressOption(opt *ExportOption) error {
+	enabledFormats := getEnabledExportFormats(opt.ExportFormats)
+	for k, v := range opt.Compress {
+		format := strings.ToLower(strings.TrimSpace(k))
+		if _, ok := enabledFormats[format]; !ok {
+			return fmt.Errorf("compress format:%v not in ExportFormats", k)
+		}
+		if _, err := getCompressExt(strings.ToLower(strings.TrimSpace(v))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 压缩方式对应的文件扩展名,和cfg.CompressExts一致
+func getCompressExt(compress string) (string, error) {
+	switch compress {
+	case "", "none":
+		return "", nil
+	case "gzip", "gz":
+		return ".gz", nil
+	case "zstd", "zst":
+		return ".zst", nil
+	}
+	return "", fmt.Errorf("unsupported compress:%v", compress)
+}
+
+// 压缩导出数据,返回压缩后的文件名和数据
+// 压缩结果是固定的,数据没有变化时md5也不会变化
+func compressData(compress, fileName string, data []byte) (string, []byte, error) {
+	ext, err := getCompressExt(compress)
+	if err != nil {
+		return fileName, nil, err
+	}
+	switch ext {
+	case ".gz":
+		buffer := bytes.NewBuffer(nil)
+		// 不设置文件头中的文件名和修改时间
+		writer, err := gzip.NewWriterLevel(buffer, gzip.BestCompression)
+		if err != nil {
+			return fileName, nil, err
+		}
+		if _, err = writer.Write(data); err != nil {
+			return fileName, nil, err
+		}
+		if err = writer.Close(); err != nil {
+			return fileName, nil, err
+		}
+		return fileName + ext, buffer.Bytes(), nil
+	case ".zst":
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		if err != nil {
+			return fileName, nil, err
+		}
+		defer encoder.Close()
+		return fileName + ext, encoder.EncodeAll(data, nil), nil
+	}
+	return fileName, data, nil
+}
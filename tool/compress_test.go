@@ -0,0 +1,69 @@
+package tool
+
+import (
+	"bytes"
+	"testing"
+
+	"excelexporter/cfg"
+)
+
+func TestCompressData(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"CfgId":1,"Name":"test"}`), 100)
+	tests := []struct {
+		compress string
+		wantName string
+	}{
+		{"", "Quests.pb"},
+		{"none", "Quests.pb"},
+		{"gzip", "Quests.pb.gz"},
+		{"zstd", "Quests.pb.zst"},
+	}
+	for _, tt := range tests {
+		fileName, compressed, err := compressData(tt.compress, "Quests.pb", data)
+		if err != nil {
+			t.Fatalf("compress:%v err:%v", tt.compress, err)
+		}
+		if fileName != tt.wantName {
+			t.Errorf("compress:%v expected %v, got %v", tt.compress, tt.wantName, fileName)
+		}
+		// 相同的数据压缩结果相同,md5不变
+		_, compressed2, _ := compressData(tt.compress, "Quests.pb", data)
+		if !bytes.Equal(compressed, compressed2) {
+			t.Errorf("compress:%v result not stable", tt.compress)
+		}
+		// cfg根据扩展名自动解压
+		decompressedName, decompressed, err := cfg.DecompressData(fileName, compressed)
+		if err != nil {
+			t.Fatalf("compress:%v err:%v", tt.compress, err)
+		}
+		if decompressedName != "Quests.pb" || !bytes.Equal(decompressed, data) {
+			t.Errorf("compress:%v decompress failed name:%v", tt.compress, decompressedName)
+		}
+	}
+	if _, _, err := compressData("lz4", "Quests.pb", data); err == nil {
+		t.Error("expected error for unsupported compress")
+	}
+	exportOption := &ExportOption{
+		ExportFormats: []string{"json", "pb", "bundle"},
+		Compress:      map[string]string{"PB": "zstd", "bundle": " gzip"},
+	}
+	if err := checkCompressOption(exportOption); err != nil {
+		t.Fatal(err)
+	}
+	if exportOption.GetCompress("pb") != "zstd" || exportOption.GetCompress("bundle") != "gzip" || exportOption.GetCompress("json") != "" {
+		t.Errorf("unexpected compress: %v", exportOption.Compress)
+	}
+	exportOption.Compress = map[string]string{"pb": "xz"}
+	if err := checkCompressOption(exportOption); err == nil {
+		t.Error("expected error for unsupported compress")
+	}
+	// 不在ExportFormats中的格式
+	exportOption.Compress = map[string]string{"lua": "gzip"}
+	if err := checkCompressOption(exportOption); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	exportOption.Compress = map[string]string{"pbb": "gzip"}
+	if err := checkCompressOption(exportOption); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
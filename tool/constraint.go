@@ -0,0 +1,333 @@
+package tool
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// 列的数据校验规则
+//
+//	#Min=1#Max=100     数值范围,字符串字段限制字符数
+//	#Regex=^[a-z_]+$   字符串需要匹配的正则表达式
+//	#Required          不能为空
+//	#Unique            整列的值不能重复
+//	#In=1,2,5          值必须是其中之一,枚举字段也可以填枚举名
+//
+// repeated字段和map字段校验每个元素(map校验value),message字段不校验
+// 也可以在proto的字段选项中声明,见excelexporter.proto
+type ColumnConstraint struct {
+	Min      *float64
+	Max      *float64
+	Regex    string // 增量导出的缓存使用gob编码,所以保存正则表达式的字符串
+	Required bool
+	Unique   bool
+	In       []string
+}
+
+// 是否有需要逐个元素校验的规则
+func (c *ColumnConstraint) hasValueRule() bool {
+	return c.Min != nil || c.Max != nil || c.Regex != "" || c.Unique || len(c.In) > 0
+}
+
+// 解析校验规则的参数,name是小写的参数名,不是校验规则时返回false
+func (c *ColumnConstraint) parseArg(name, value string) (bool, error) {
+	switch name {
+	case "min", "max":
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return true, fmt.Errorf("#%v must be number:%q", name, value)
+		}
+		if name == "min" {
+			c.Min = &f
+		} else {
+			c.Max = &f
+		}
+	case "regex":
+		if _, err := regexp.Compile(value); err != nil {
+			return true, fmt.Errorf("#Regex err:%w", err)
+		}
+		c.Regex = value
+	case "required":
+		c.Required = true
+	case "unique":
+		c.Unique = true
+	case "in":
+		c.In = nil
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				c.In = append(c.In, s)
+			}
+		}
+		if len(c.In) == 0 {
+			return true, fmt.Errorf("#In is empty")
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// 一个sheet转换过程中的校验状态,用于#Unique和message选项unique_key检查
+type constraintChecker struct {
+	uniqueValues map[string]map[string]string // 列名 -> 值 -> 第一次出现的单元格
+	uniqueKeys   map[string]map[string]string // unique_key的字段名 -> 字段值的组合 -> 第一次出现的单元格
+	regexps      map[string]*regexp.Regexp
+}
+
+func newConstraintChecker() *constraintChecker {
+	return &constraintChecker{
+		uniqueValues: make(map[string]map[string]string),
+		uniqueKeys:   make(map[string]map[string]string),
+		regexps:      make(map[string]*regexp.Regexp),
+	}
+}
+
+func (checker *constraintChecker) getRegexp(expr string) *regexp.Regexp {
+	re, ok := checker.regexps[expr]
+	if !ok {
+		re = regexp.MustCompile(expr) // 解析列名时已经检查过
+		checker.regexps[expr] = re
+	}
+	return re
+}
+
+// 校验一个单元格,value是单元格转换后的值,单元格为空或者转换失败时为nil
+// 列名中的校验规则和proto字段选项中的校验规则都会检查,嵌套的子message按字段选项检查
+func (checker *constraintChecker) checkCell(ctx *CellContext, fieldDesc *desc.FieldDescriptor, columnOpt *ColumnOption, cell string, value any) {
+	var fieldConstraint *ColumnConstraint
+	if rule := getFieldRule(fieldDesc); rule != nil {
+		fieldConstraint = rule.Constraint
+	}
+	constraint := mergeConstraint(columnOpt.Constraint, fieldConstraint)
+	if cell == "" {
+		if constraint != nil && constraint.Required {
+			ctx.Errorf("required value is empty")
+		}
+		return
+	}
+	if value == nil {
+		return
+	}
+	subMsgDesc := getValueMessageType(fieldDesc)
+	if (constraint == nil || !constraint.hasValueRule()) && (subMsgDesc == nil || !messageHasFieldRule(subMsgDesc)) {
+		return
+	}
+	checker.checkFieldValue(ctx, columnOpt.Name, "", fieldDesc, constraint, value)
+}
+
+// 校验字段的值,repeated字段校验每个元素,map字段校验每个value,message按字段选项校验子字段
+// prefix用于在诊断信息中标记嵌套的子字段
+func (checker *constraintChecker) checkFieldValue(ctx *CellContext, fieldPath, prefix string, fieldDesc *desc.FieldDescriptor, constraint *ColumnConstraint, value any) {
+	if value == nil {
+		return
+	}
+	if list, ok := value.([]any); ok {
+		for _, elem := range list {
+			checker.checkFieldValue(ctx, fieldPath, prefix, fieldDesc, constraint, elem)
+		}
+		return
+	}
+	if fieldDesc.IsMap() {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Map {
+			return
+		}
+		valueDesc := fieldDesc.GetMapValueType()
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessMapKey(keys[i], keys[j])
+		})
+		for _, key := range keys {
+			checker.checkFieldValue(ctx, fieldPath, prefix, valueDesc, constraint, rv.MapIndex(key).Interface())
+		}
+		return
+	}
+	if msgDesc := fieldDesc.GetMessageType(); msgDesc != nil {
+		if m, ok := value.(map[string]any); ok && messageHasFieldRule(msgDesc) {
+			checker.checkMessage(ctx, fieldPath, msgDesc, m)
+		}
+		return
+	}
+	if constraint != nil {
+		checker.checkValue(ctx, fieldPath, prefix, fieldDesc, constraint, value)
+	}
+}
+
+// 按字段选项校验message的子字段
+func (checker *constraintChecker) checkMessage(ctx *CellContext, msgPath string, msgDesc *desc.MessageDescriptor, m map[string]any) {
+	for _, fieldDesc := range msgDesc.GetFields() {
+		var constraint *ColumnConstraint
+		if rule := getFieldRule(fieldDesc); rule != nil {
+			constraint = rule.Constraint
+		}
+		subMsgDesc := getValueMessageType(fieldDesc)
+		if constraint == nil && (subMsgDesc == nil || !messageHasFieldRule(subMsgDesc)) {
+			continue
+		}
+		fieldPath := msgPath + "." + fieldDesc.GetName()
+		value, ok := m[fieldDesc.GetJSONName()]
+		if !ok {
+			value = m[fieldDesc.GetName()] // #Format=json可能使用proto字段名
+		}
+		if value == nil {
+			if constraint != nil && constraint.Required {
+				ctx.Errorf("field:%v required value is empty", fieldPath)
+			}
+			continue
+		}
+		checker.checkFieldValue(ctx, fieldPath, "field:"+fieldPath+" ", fieldDesc, constraint, value)
+	}
+}
+
+func (checker *constraintChecker) checkValue(ctx *CellContext, fieldPath, prefix string, fieldDesc *desc.FieldDescriptor, constraint *ColumnConstraint, value any) {
+	s, _ := refValueString(value)
+	if constraint.Min != nil || constraint.Max != nil {
+		var (
+			f  float64
+			ok = true
+		)
+		switch v := value.(type) {
+		case string:
+			f = float64(utf8.RuneCountInString(v))
+		case bool:
+			ok = false
+		default:
+			rv := reflect.ValueOf(value)
+			switch rv.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				f = float64(rv.Int())
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				f = float64(rv.Uint())
+			case reflect.Float32, reflect.Float64:
+				f = rv.Float()
+			default:
+				ok = false
+			}
+		}
+		if ok && constraint.Min != nil && f < *constraint.Min {
+			ctx.Errorf("%vvalue %v less than min %v", prefix, s, *constraint.Min)
+		}
+		if ok && constraint.Max != nil && f > *constraint.Max {
+			ctx.Errorf("%vvalue %v greater than max %v", prefix, s, *constraint.Max)
+		}
+	}
+	if constraint.Regex != "" && !checker.getRegexp(constraint.Regex).MatchString(s) {
+		ctx.Errorf("%vvalue %q not match regex %v", prefix, s, constraint.Regex)
+	}
+	if len(constraint.In) > 0 && !constraintInContains(fieldDesc, constraint.In, s) {
+		ctx.Errorf("%vvalue %v not in [%v]", prefix, s, strings.Join(constraint.In, ","))
+	}
+	if constraint.Unique {
+		values, ok := checker.uniqueValues[fieldPath]
+		if !ok {
+			values = make(map[string]string)
+			checker.uniqueValues[fieldPath] = values
+		}
+		if firstCell, ok := values[s]; ok {
+			ctx.Errorf("%vduplicate value %v, first at %v", prefix, s, firstCell)
+		} else {
+			values[s] = ctx.CellName()
+		}
+	}
+}
+
+// 按message选项unique_key检查一行数据,字段都没有值的行不检查
+func (checker *constraintChecker) checkUniqueKeys(ctx *CellContext, msgDesc *desc.MessageDescriptor, columnOpts []*ColumnOption, rowValue map[string]any) {
+	rule := getMessageRule(msgDesc)
+	if rule == nil {
+		return
+	}
+	for _, keyFields := range rule.UniqueKeys {
+		names := make([]string, 0, len(keyFields))
+		values := make([]string, 0, len(keyFields))
+		hasValue := false
+		for _, fieldDesc := range keyFields {
+			value, ok := rowValue[fieldDesc.GetJSONName()]
+			hasValue = hasValue || (ok && value != nil)
+			s, _ := refValueString(value)
+			names = append(names, fieldDesc.GetName())
+			values = append(values, s)
+		}
+		if !hasValue {
+			continue
+		}
+		keyName := strings.Join(names, ",")
+		keyValue := strings.Join(values, ",")
+		keyValues, ok := checker.uniqueKeys[keyName]
+		if !ok {
+			keyValues = make(map[string]string)
+			checker.uniqueKeys[keyName] = keyValues
+		}
+		ctx.Column = findColumnOptionByField(msgDesc, columnOpts, keyFields[0])
+		if firstCell, ok := keyValues[keyValue]; ok {
+			ctx.Errorf("duplicate unique_key %v value %v, first at %v", keyName, keyValue, firstCell)
+		} else {
+			keyValues[keyValue] = ctx.CellName()
+		}
+	}
+}
+
+// #In的值可以是枚举名
+func constraintInContains(fieldDesc *desc.FieldDescriptor, in []string, s string) bool {
+	for _, v := range in {
+		if v == s {
+			return true
+		}
+		if enumDesc := fieldDesc.GetEnumType(); enumDesc != nil {
+			if enumValueDesc := enumDesc.FindValueByName(v); enumValueDesc != nil && strconv.Itoa(int(enumValueDesc.GetNumber())) == s {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// object格式的配置每行是一个字段,校验时用字段名作为列名,Value列的校验规则对每个字段都生效
+func getObjectCheckColumn(valueColumnOpt *ColumnOption, fieldName string) *ColumnOption {
+	checkOpt := *valueColumnOpt
+	checkOpt.Name = fieldName
+	checkOpt.parseExpandName()
+	return &checkOpt
+}
+
+// 单元格转换后的值在rowValue中的key,和SetFieldValue一致
+func getColumnValueKey(fieldDesc *desc.FieldDescriptor, columnOpt *ColumnOption) string {
+	if columnOpt.Merge {
+		return columnOpt.MergeKey
+	}
+	if columnOpt.IsExpand() {
+		return columnOpt.Name
+	}
+	return fieldDesc.GetJSONName()
+}
+
+// proto字段选项中声明了required的字段,配置表中必须有对应的列
+// object格式的配置columnOpts是每行的字段,必须有对应的行
+func checkRequiredFieldColumns(msgDesc *desc.MessageDescriptor, opt *SheetOption, columnOpts []*ColumnOption, diags *Diagnostics) {
+	for _, fieldDesc := range msgDesc.GetFields() {
+		rule := getFieldRule(fieldDesc)
+		if rule == nil || rule.Constraint == nil || !rule.Constraint.Required {
+			continue
+		}
+		hasColumn := false
+		for _, columnOpt := range columnOpts {
+			if FindFieldDescriptor(msgDesc, columnOpt.Name) == fieldDesc ||
+				(columnOpt.IsExpand() && FindFieldDescriptor(msgDesc, strings.Split(columnOpt.ExpandName, ".")[0]) == fieldDesc) {
+				hasColumn = true
+				break
+			}
+		}
+		if !hasColumn && opt.MgrType == "object" {
+			diags.Errorf(opt.ExcelName, opt.SheetName, "required field %v has no row", fieldDesc.GetName())
+		} else if !hasColumn {
+			diags.Errorf(opt.ExcelName, opt.SheetName, "required field %v has no column", fieldDesc.GetName())
+		}
+	}
+}
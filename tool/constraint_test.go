@@ -0,0 +1,157 @@
+package tool
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConvertColumnOption_Constraint(t *testing.T) {
+	opt := ConvertColumnOption("Name#Required#Unique#Min=1#Max=10#Regex=^[a-z_=]+$#In=a, b")
+	if opt == nil || opt.Constraint == nil {
+		t.Fatalf("constraint not parsed: %+v", opt)
+	}
+	c := opt.Constraint
+	if !c.Required || !c.Unique || *c.Min != 1 || *c.Max != 10 || c.Regex != "^[a-z_=]+$" || !reflect.DeepEqual(c.In, []string{"a", "b"}) {
+		t.Fatalf("unexpected constraint: %+v", c)
+	}
+	if opt = ConvertColumnOption("CfgId#Ref=Item"); opt.Constraint != nil {
+		t.Fatalf("unexpected constraint: %+v", opt.Constraint)
+	}
+	// 正则表达式中的#
+	opt = ConvertColumnOption("Code#Regex=^#[0-9]+(#[a-z]+)?$#Required")
+	if opt == nil || opt.Constraint == nil || opt.Constraint.Regex != "^#[0-9]+(#[a-z]+)?$" || !opt.Constraint.Required {
+		t.Fatalf("unexpected constraint: %+v", opt)
+	}
+	if opt = ConvertColumnOption("Code#Required#Regex=^#$"); opt == nil || opt.Constraint.Regex != "^#$" || !opt.Constraint.Required {
+		t.Fatalf("unexpected constraint: %+v", opt)
+	}
+	for _, input := range []string{"CfgId#Min=a", "Name#Regex=[a-", "CfgId#In="} {
+		if opt = ConvertColumnOption(input); opt != nil {
+			t.Fatalf("expected nil for invalid constraint %v", input)
+		}
+	}
+}
+
+func TestConvertSheetConstraint(t *testing.T) {
+	initProtoForTest(t)
+	initTempProtoForTest(t, "types_test.proto", typesTestProto)
+	f := newTestSheetFile(t, "Quest",
+		[]interface{}{"CfgId#Unique", "Name#Required#Regex=^[a-z_]+$", "Detail#Min=1#Max=3", "QuestType#In=0,1", "NextQuests#Max=10#Unique", "Properties#Regex=^\\d+$"},
+		[]interface{}{"1", "main", "好的", "1", "2;3", "a_1"},
+		[]interface{}{"2", "Side", "abcd", "2", "11;4", "b_x"},
+		[]interface{}{"1", "", "", "0", "3"},
+	)
+	defer func() { _ = f.Close() }()
+	opt := &SheetOption{
+		SheetName:   "Quest",
+		MessageName: "QuestCfg",
+		MgrType:     "slice",
+	}
+	diags := NewDiagnostics()
+	data, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), opt, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows := data.([]any); len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %v", rows)
+	}
+	var got []string
+	for _, diag := range diags.Items() {
+		if diag.Severity != SeverityError {
+			t.Fatalf("unexpected severity: %v", diag)
+		}
+		got = append(got, diag.Cell+" "+diag.Message)
+	}
+	want := []string{
+		"B3 value \"Side\" not match regex ^[a-z_]+$",
+		"C3 value abcd greater than max 3",
+		"D3 value 2 not in [0,1]",
+		"E3 value 11 greater than max 10",
+		"F3 value \"x\" not match regex ^\\d+$",
+		"A4 duplicate value 1, first at A2",
+		"B4 required value is empty",
+		"E4 duplicate value 3, first at E2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+
+	// 枚举字段的#In可以填枚举名
+	f2 := newTestSheetFile(t, "Types",
+		[]interface{}{"CfgId", "ColorValue#In=Color_Red,Color_Blue"},
+		[]interface{}{"1", "Color_Red"},
+		[]interface{}{"2", "Color_Green"},
+	)
+	defer func() { _ = f2.Close() }()
+	diags = NewDiagnostics()
+	_, err = ConvertSourceSheet(&ExportOption{}, NewExcelSource(f2), &SheetOption{SheetName: "Types", MessageName: "TestCfgTypes", MgrType: "map"}, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := diags.Items(); len(items) != 1 || items[0].Cell != "B3" {
+		t.Fatalf("unexpected diags: %v", items)
+	}
+}
+
+func TestConvertSheetConstraintObject(t *testing.T) {
+	initTempProtoForTest(t, "types_test.proto", typesTestProto)
+	f := newTestSheetFile(t, "TypesObj",
+		[]interface{}{"Key", "Value#Required#Min=1#Max=10"},
+		[]interface{}{"CfgId", "20"},
+		[]interface{}{"Int64Value", "5"},
+		[]interface{}{"Int32Values", "0;3"},
+		[]interface{}{"Uint32Value"},
+	)
+	defer func() { _ = f.Close() }()
+	diags := NewDiagnostics()
+	data, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), &SheetOption{SheetName: "TypesObj", MessageName: "TestCfgTypes", MgrType: "object"}, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := data.(map[string]any)["Int64Value"]; v != int64(5) {
+		t.Fatalf("unexpected data: %v", data)
+	}
+	var got []string
+	for _, diag := range diags.Items() {
+		got = append(got, diag.Cell+" "+diag.Message)
+	}
+	want := []string{
+		"B2 value 20 greater than max 10",
+		"B4 value 0 less than min 1",
+		"B5 required value is empty",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestCheckMergeUniqueValues(t *testing.T) {
+	initTempProtoForTest(t, "types_test.proto", typesTestProto)
+	convert := func(excelName string, rows ...[]interface{}) *SheetOption {
+		f := newTestSheetFile(t, "Types", append([][]interface{}{{"CfgId", "Int64Value#Unique"}}, rows...)...)
+		defer func() { _ = f.Close() }()
+		_, resultOpt, err := convertSheet(&ExportOption{}, NewExcelSource(f),
+			&SheetOption{ExcelName: excelName, SheetName: "Types", MessageName: "TestCfgTypes", MgrType: "slice"},
+			FindMessageDescriptor("TestCfgTypes"), NewDiagnostics())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resultOpt
+	}
+	opt1 := convert("types1.xlsx", []interface{}{"1", "10"}, []interface{}{"2", "20"})
+	opt2 := convert("types2.xlsx", []interface{}{"3", "30"}, []interface{}{"4", "10"})
+	if !reflect.DeepEqual(opt2.UniqueCells, map[string]map[string]string{"Int64Value": {"30": "B2", "10": "B3"}}) {
+		t.Fatalf("unexpected unique cells: %v", opt2.UniqueCells)
+	}
+	diags := NewDiagnostics()
+	uniqueSources := make(map[string]map[string]string)
+	checkMergeUniqueValues(uniqueSources, opt1, "Types", diags)
+	checkMergeUniqueValues(uniqueSources, opt2, "Types", diags)
+	items := diags.Items()
+	if len(items) != 1 {
+		t.Fatalf("unexpected diags: %v", items)
+	}
+	if got := items[0].String(); got != "[ERROR] types2.xlsx Types!B3 column:Int64Value duplicate value 10 in merge:Types, first at types1.xlsx Types B2" {
+		t.Fatalf("unexpected diag: %v", got)
+	}
+}
@@ -1,9 +1,13 @@
 package tool
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -18,11 +22,20 @@ type SheetOption struct {
 	ExcelName      string
 	SheetName      string
 	MessageName    string
-	MgrType        string // map slice object
-	MapKeyName     string // 填空直接使用第一个非注释列作为key名(MgrType=map时才有效)
-	MapKeyType     string // int int32 int64 uint uint32 uint64 string(MgrType=map时才有效)
+	MgrType        string // map slice object group
+	MapKeyName     string // 填空直接使用第一个非注释列作为key名(MgrType=map group时才有效)
+	MapKeyType     string // int int32 int64 uint uint32 uint64 string(MgrType=map group时才有效)
 	ExportFileName string // 填空直接使用SheetName作为文件名
+	Layout         string // 表格布局,为空:每行一条数据 multirow:key列为空的行是上一行的续行 vertical:竖表,每列一条数据
 	ColumnOpts     []*ColumnOption
+	KeyCells       map[string]string            // MgrType=map时,每个key所在的单元格(转换后才有),用于检查合并的sheet中重复的key
+	RowIndexes     map[string][]int             // 每条数据所在的行号(转换后才有,竖表是列号),key:slice的下标或者map的key,group一个key对应多行,用于关联检查定位单元格
+	UniqueCells    map[string]map[string]string // #Unique的列中每个值所在的单元格(转换后才有),key:列名或者子字段路径,用于检查合并的sheet中重复的值
+}
+
+// 需要key列的管理器类型,map:一个key对应一行,group:一个key对应多行
+func isKeyedMgrType(mgrType string) bool {
+	return mgrType == "map" || mgrType == "group"
 }
 
 type ColumnOption struct {
@@ -62,12 +75,26 @@ type ColumnOption struct {
 	//	----------------------------------------
 	//	| 1        | abc         | it's a test |
 	//	----------------------------------------
+	// 支持多层展开,如Base.Pos.X,ExpandName为Base.Pos,ExpandFieldName为X
 	ExpandName      string // 展开的字段,如注释中的Child
 	ExpandFieldName string // 展开的字段的字段名,如注释中的Id和Name
+	Indexed         bool   // 列名中有repeated字段的下标,如Rewards[0].CfgId,NextQuests[1]
 
 	Merge    bool   // 是否参与数组合并,用于repeated字段的多列合并
 	MergeKey string // Merge列在rowValue中的唯一存储key
 	Sep      string // 自定义字段的第一层分隔符,通过#Sep=|指定,默认为"_"
+
+	Constraint *ColumnConstraint // 数据校验规则,如#Min=1#Max=100#Required
+}
+
+// 拷贝一份,列定义会在转换时重新解析,所以不拷贝ColumnOpts,KeyCells,RowIndexes和UniqueCells
+func (opt *SheetOption) clone() *SheetOption {
+	newOpt := *opt
+	newOpt.ColumnOpts = nil
+	newOpt.KeyCells = nil
+	newOpt.RowIndexes = nil
+	newOpt.UniqueCells = nil
+	return &newOpt
 }
 
 func (c *ColumnOption) GetSep() string {
@@ -92,7 +119,32 @@ func (c *ColumnOption) IsFullFieldName() bool {
 
 // 是否是展开字段
 func (c *ColumnOption) IsExpand() bool {
-	return c.ExpandName != ""
+	return c.ExpandName != "" || c.Indexed
+}
+
+// 是否是repeated字段的一个元素,如NextQuests[1],Rewards[0]
+func (c *ColumnOption) IsIndexedElem() bool {
+	return c.Indexed && strings.HasSuffix(c.Name, "]")
+}
+
+// 根据列名设置展开字段的信息
+func (c *ColumnOption) parseExpandName() {
+	c.ExpandName, c.ExpandFieldName = splitExpandName(c.Name)
+	c.Indexed = hasColumnIndex(c.Name)
+}
+
+// 把展开字段的列名拆分成父字段路径和字段名,如Base.Pos.X拆分成Base.Pos和X,不是展开字段时返回空
+func splitExpandName(name string) (string, string) {
+	idx := strings.LastIndex(name, ".")
+	if idx <= 0 || idx == len(name)-1 {
+		return "", ""
+	}
+	for _, childName := range strings.Split(name, ".") {
+		if childName == "" {
+			return "", ""
+		}
+	}
+	return name[:idx], name[idx+1:]
 }
 
 // ColumnName#format=json#arg=value
@@ -106,20 +158,14 @@ func ConvertColumnOption(cell string) *ColumnOption {
 	//	---------------------------------------------
 	//	| 1_3       | 1_3             | Id_1#Count_3 |
 	//	---------------------------------------------
-	nameAndArgs := strings.Split(cell, "#")
+	nameAndArgs := splitColumnArgs(cell)
 	if len(nameAndArgs) == 0 {
 		return nil
 	}
 	opt := &ColumnOption{
 		Name: nameAndArgs[0],
 	}
-	if strings.Index(opt.Name, ".") > 0 {
-		expandNames := strings.Split(opt.Name, ".")
-		if len(expandNames) == 2 {
-			opt.ExpandName = expandNames[0]
-			opt.ExpandFieldName = expandNames[1]
-		}
-	}
+	opt.parseExpandName()
 	for i := 1; i < len(nameAndArgs); i++ {
 		arg := nameAndArgs[i]
 		kv := strings.Split(arg, "=")
@@ -145,36 +191,135 @@ func ConvertColumnOption(cell string) *ColumnOption {
 			if len(kv) == 2 {
 				opt.Sep = kv[1]
 			}
+		default:
+			// 数据校验规则,#Regex的正则表达式中可以有=
+			constraint := opt.Constraint
+			if constraint == nil {
+				constraint = &ColumnConstraint{}
+			}
+			value := ""
+			if idx := strings.Index(arg, "="); idx >= 0 {
+				value = arg[idx+1:]
+			}
+			ok, err := constraint.parseArg(strings.ToLower(kv[0]), value)
+			if err != nil {
+				color.Red("column:%v err:%v", cell, err)
+				return nil
+			}
+			if ok {
+				opt.Constraint = constraint
+			}
 		}
 	}
 	return opt
 }
 
+// 列名中的参数名
+var columnArgNames = map[string]struct{}{
+	"field": {}, "format": {}, "ref": {}, "merge": {}, "sep": {},
+	"min": {}, "max": {}, "regex": {}, "required": {}, "unique": {}, "in": {},
+}
+
+// 用#分隔列名和参数,#Regex的正则表达式中可以有#,如Code#Regex=^#[0-9]+$#Required
+// 正则表达式中的#后面不是参数名时,属于正则表达式
+func splitColumnArgs(cell string) []string {
+	parts := strings.Split(cell, "#")
+	result := make([]string, 0, len(parts))
+	result = append(result, parts[0])
+	inRegex := false
+	for _, part := range parts[1:] {
+		name, _, _ := strings.Cut(part, "=")
+		name = strings.ToLower(name)
+		if _, ok := columnArgNames[name]; inRegex && !ok {
+			result[len(result)-1] += "#" + part
+			continue
+		}
+		inRegex = name == "regex"
+		result = append(result, part)
+	}
+	return result
+}
+
 // opt.MgrType="map"时,返回map[key]any
 // opt.MgrType="slice"时,返回[]any
+// opt.MgrType="group"时,返回map[key]any,value是相同key的行组成的[]any
+// 转换过程中的问题直接输出到控制台,需要收集诊断信息时使用ConvertSourceSheet
 func ConvertSheet(exportOption *ExportOption, excelFile *excelize.File, opt *SheetOption) (any, error) {
-	msgDesc := FindMessageDescriptor(opt.MessageName)
+	return ConvertSourceSheet(exportOption, NewExcelSource(excelFile), opt, nil)
+}
+
+// 转换数据源文件(xlsx,csv,tsv)中的sheet,csv和tsv文件忽略sheet名
+// 转换过程中的问题记录到diags,diags为nil时直接输出到控制台
+func ConvertSourceSheet(exportOption *ExportOption, source SourceFile, opt *SheetOption, diags *Diagnostics) (any, error) {
+	data, _, err := convertSheet(exportOption, source, opt, FindMessageDescriptor(opt.MessageName), diags)
+	return data, err
+}
+
+// 返回转换后的数据和解析后的sheet设置(包含列定义和map key信息)
+// 不会修改传入的opt,可以并发调用
+func convertSheet(exportOption *ExportOption, source SourceFile, sheetOption *SheetOption, msgDesc *desc.MessageDescriptor, diags *Diagnostics) (any, *SheetOption, error) {
 	if msgDesc == nil {
-		return nil, fmt.Errorf("message %s not found, sheet:%v", opt.MessageName, opt.SheetName)
+		return nil, nil, fmt.Errorf("message %s not found, sheet:%v", sheetOption.MessageName, sheetOption.SheetName)
 	}
+	opt := sheetOption.clone()
+	opt.RowIndexes = make(map[string][]int)
 	var mapKeyFieldDesc *desc.FieldDescriptor
-	if opt.MgrType == "map" {
+	var compositeKeyFieldDescs []*desc.FieldDescriptor // 组合key的字段
+	if isKeyedMgrType(opt.MgrType) && isCompositeMapKey(opt.MapKeyName) {
+		opt.KeyCells = make(map[string]string)
+		var keyErr error
+		compositeKeyFieldDescs, keyErr = findCompositeKeyFields(msgDesc, opt.MapKeyName)
+		if keyErr != nil {
+			color.Red("sheet:%v err:%v", opt.SheetName, keyErr)
+			return nil, nil, keyErr
+		}
+		keyNames := make([]string, 0, len(compositeKeyFieldDescs))
+		for _, keyFieldDesc := range compositeKeyFieldDescs {
+			keyNames = append(keyNames, keyFieldDesc.GetJSONName())
+		}
+		opt.MapKeyName = strings.Join(keyNames, ",")
+		opt.MapKeyType = "string" // 组合key导出为字符串,如"1_2"
+		mapKeyFieldDesc = compositeKeyFieldDescs[0]
+	} else if isKeyedMgrType(opt.MgrType) {
+		opt.KeyCells = make(map[string]string)
 		mapKeyFieldDesc = FindFieldDescriptor(msgDesc, opt.MapKeyName)
 		if mapKeyFieldDesc != nil {
-			opt.MapKeyName = mapKeyFieldDesc.GetJSONName() // 因为要导出为json格式,所以用json名
-			if strings.Index(opt.MapKeyName, ".") > 0 {
-				names := strings.Split(opt.MapKeyName, ".") // child.fieldName
-				opt.MapKeyName = names[0] + "." + mapKeyFieldDesc.GetJSONName()
+			if expandName, _ := splitExpandName(opt.MapKeyName); expandName != "" {
+				opt.MapKeyName = expandName + "." + mapKeyFieldDesc.GetJSONName() // child.fieldName
+			} else {
+				opt.MapKeyName = mapKeyFieldDesc.GetJSONName() // 因为要导出为json格式,所以用json名
 			}
 		}
 		if opt.MapKeyType == "" && mapKeyFieldDesc != nil {
 			opt.MapKeyType = GetKeyTypeString(mapKeyFieldDesc)
 		}
 	}
-	rows, err := excelFile.Rows(opt.SheetName)
+	if (opt.Layout != "" && opt.Layout != layoutMultiRow && opt.Layout != layoutVertical) ||
+		(opt.Layout == layoutVertical && opt.MgrType == "object") {
+		err := fmt.Errorf("unsupported Layout %v MgrType:%v sheet:%v", opt.Layout, opt.MgrType, opt.SheetName)
+		color.Red("%v", err)
+		return nil, nil, err
+	}
+	isMultiRow := opt.Layout == layoutMultiRow && opt.MgrType != "object"
+	location, err := exportOption.GetTimeLocation()
 	if err != nil {
 		color.Red("sheet:%v err:%v", opt.SheetName, err)
-		return nil, err
+		return nil, nil, err
+	}
+	rows, err := source.Rows(opt.SheetName)
+	if err != nil {
+		color.Red("sheet:%v err:%v", opt.SheetName, err)
+		return nil, nil, err
+	}
+	if sheetRows, ok := rows.(*excelRows); ok {
+		sheetRows.calcFormula = exportOption.CalcFormula
+	}
+	if opt.Layout == layoutVertical {
+		// 竖表行列互换后,和普通的配置表一样解析
+		if rows, err = newTransposedRows(rows); err != nil {
+			color.Red("sheet:%v err:%v", opt.SheetName, err)
+			return nil, nil, err
+		}
 	}
 	defer func() {
 		if err = rows.Close(); err != nil {
@@ -183,16 +328,21 @@ func ConvertSheet(exportOption *ExportOption, excelFile *excelize.File, opt *She
 	}()
 	hasParseExportGroupRow := false
 	fieldNameNotFoundMap := make(map[string]struct{})
+	checker := newConstraintChecker()
 	opt.ColumnOpts = make([]*ColumnOption, 0)
 	m := make(map[any]any)
 	s := make([]any, 0)
+	var multiRowKeyColumns []*ColumnOption // 多行格式中用来判断续行的key列
+	var lastRowValue map[string]any        // 多行格式中续行追加到的数据
+	objectOneOfKinds := make(map[string]string)
+	var objectColumns []*ColumnOption // object格式的配置中每行对应的字段,用于检查required
 	rowIdx := -1
 	for rows.Next() {
 		rowIdx++
 		row, err := rows.Columns()
 		if err != nil {
 			color.Red("sheet:%v err:%v", opt.SheetName, err)
-			return nil, err
+			return nil, nil, err
 		}
 		if len(row) == 0 {
 			//fmt.Println(fmt.Sprintf("empty row, sheet:%v", opt.SheetName))
@@ -214,15 +364,20 @@ func ConvertSheet(exportOption *ExportOption, excelFile *excelize.File, opt *She
 				}
 				columnOpt := ConvertColumnOption(columnName)
 				if columnOpt == nil {
-					return nil, errors.New(fmt.Sprintf("columnName err %v sheet:%v", columnName, opt.SheetName))
+					return nil, nil, errors.New(fmt.Sprintf("columnName err %v sheet:%v", columnName, opt.SheetName))
 				}
 				columnOpt.ColumnIndex = columnIndex
+				if isMultiRow {
+					// 多行格式中,repeated的message字段的展开列每行配置一个元素,如Conditions.Type
+					columnOpt.Name = normalizeMultiRowColumnName(msgDesc, columnOpt.Name)
+					columnOpt.parseExpandName()
+				}
 				if columnOpt.Merge {
 					columnOpt.MergeKey = fmt.Sprintf("__merge_%s_%d__", columnOpt.Name, columnIndex)
 				}
 				opt.ColumnOpts = append(opt.ColumnOpts, columnOpt)
 				// 如果没有指定MapKey,则默认第一个非注释列为MapKey
-				if opt.MgrType == "map" && opt.MapKeyName == "" {
+				if isKeyedMgrType(opt.MgrType) && opt.MapKeyName == "" {
 					opt.MapKeyName = columnOpt.Name
 					mapKeyFieldDesc = FindFieldDescriptor(msgDesc, opt.MapKeyName)
 					if mapKeyFieldDesc != nil {
@@ -237,6 +392,9 @@ func ConvertSheet(exportOption *ExportOption, excelFile *excelize.File, opt *She
 				}
 			}
 			//fmt.Println(fmt.Sprintf("keyName:%v keyType:%v", opt.MapKeyName, opt.MapKeyType))
+			if isMultiRow {
+				multiRowKeyColumns = getMultiRowKeyColumns(msgDesc, opt, mapKeyFieldDesc, compositeKeyFieldDescs)
+			}
 			continue
 		}
 		// 解析导出分组标记(object类型使用group列按行过滤,不需要##group行)
@@ -260,6 +418,16 @@ func ConvertSheet(exportOption *ExportOption, excelFile *excelize.File, opt *She
 		}
 		// 解析数据行
 		rowValue := make(map[string]any)
+		oneOfKinds := make(map[string]string) // oneof的Kind列的值
+		cellCtx := &CellContext{
+			Diags:     diags,
+			ExcelName: opt.ExcelName,
+			SheetName: opt.SheetName,
+			RowIndex:  rowIdx,
+			Strict:    exportOption.StrictParse,
+			Vertical:  opt.Layout == layoutVertical,
+			Location:  location,
+		}
 		// Key-Value格式的配置格式 特殊处理
 		if opt.MgrType == "object" {
 			var (
@@ -279,18 +447,21 @@ func ConvertSheet(exportOption *ExportOption, excelFile *excelize.File, opt *She
 				}
 			}
 			if keyColumnOpt == nil {
-				color.Red("key column not found: %v", opt.SheetName)
+				diags.Errorf(opt.ExcelName, opt.SheetName, "key column not found")
 				continue
 			}
 			if valueColumnOpt == nil {
-				color.Red("value column not found: %v", opt.SheetName)
+				diags.Errorf(opt.ExcelName, opt.SheetName, "value column not found")
 				continue
 			}
-			if keyColumnOpt.ColumnIndex >= len(row) || valueColumnOpt.ColumnIndex >= len(row) {
+			if keyColumnOpt.ColumnIndex >= len(row) {
 				continue // 跳过空的cell
 			}
 			fieldName := strings.TrimSpace(row[keyColumnOpt.ColumnIndex])
-			cell := strings.TrimSpace(row[valueColumnOpt.ColumnIndex]) // 移除首尾的空字符串
+			cell := ""
+			if valueColumnOpt.ColumnIndex < len(row) {
+				cell = strings.TrimSpace(row[valueColumnOpt.ColumnIndex]) // 移除首尾的空字符串
+			}
 			if exportOption.ExportGroup != "" {
 				fieldGroup := ""
 				if groupColumnOpt != nil && groupColumnOpt.ColumnIndex < len(row) {
@@ -304,127 +475,239 @@ func ConvertSheet(exportOption *ExportOption, excelFile *excelize.File, opt *She
 				}
 			}
 			fieldDesc := FindFieldDescriptor(msgDesc, fieldName)
+			if valueColumnOpt.ColumnIndex >= len(row) {
+				// 空的cell也需要检查#Required
+				if fieldDesc != nil {
+					checkColumn := getObjectCheckColumn(valueColumnOpt, fieldName)
+					objectColumns = append(objectColumns, checkColumn)
+					cellCtx.Column = valueColumnOpt
+					checker.checkCell(cellCtx, fieldDesc, checkColumn, "", nil)
+				}
+				continue // 跳过空的cell
+			}
+			if fieldDesc == nil && findOneOfDescriptor(msgDesc, fieldName) != nil {
+				objectOneOfKinds[fieldName] = cell
+				continue
+			}
 			if fieldDesc == nil {
 				fieldNameNotFoundMap[fieldName] = struct{}{}
 				//fmt.Println(fmt.Sprintf("FieldNameNotFound %v row%v name:%s sheet:%v", opt.ExcelName, rowIdx, fieldName, opt.SheetName))
 				continue
 			}
+			checkColumn := getObjectCheckColumn(valueColumnOpt, fieldName)
+			objectColumns = append(objectColumns, checkColumn)
 			columnOpt := valueColumnOpt
+			if hasColumnIndex(fieldName) {
+				// 带下标的key,如Rewards[0].CfgId,按展开字段转换
+				indexedOpt := *valueColumnOpt
+				indexedOpt.Name = fieldName
+				indexedOpt.parseExpandName()
+				columnOpt = &indexedOpt
+			}
+			cellCtx.Column = columnOpt
 			// format扩展 json
 			if columnOpt.Format == "json" {
-				err = SetFieldValueJson(rowValue, fieldDesc, columnOpt, cell)
+				err = setFieldValueJson(cellCtx, rowValue, fieldDesc, columnOpt, cell)
 				if err != nil {
-					color.Red("SetFieldValueJsonErr row%v sheet:%v err:%v", rowIdx, opt.SheetName, err)
+					cellCtx.Errorf("SetFieldValueJsonErr key:%v err:%v", fieldName, err)
 					continue
 				}
 			} else {
-				err = SetFieldValue(rowValue, fieldDesc, columnOpt, cell, false)
+				err = setFieldValue(cellCtx, rowValue, fieldDesc, columnOpt, cell, false)
 				if err != nil {
-					color.Red("SetFieldValueErr row%v sheet:%v err:%v", rowIdx, opt.SheetName, err)
+					cellCtx.Errorf("SetFieldValueErr key:%v err:%v", fieldName, err)
 					continue
 				}
 			}
-			if v, ok := rowValue[fieldDesc.GetJSONName()]; ok {
+			if v, ok := rowValue[getColumnValueKey(fieldDesc, columnOpt)]; ok {
 				m[fieldName] = v
+				// 数据校验
+				checker.checkCell(cellCtx, fieldDesc, checkColumn, cell, v)
 			} else {
-				color.Red("value convert err row%v sheet:%v key:%v value:%v", rowIdx, opt.SheetName, fieldName, cell)
+				cellCtx.Errorf("value convert err key:%v value:%v", fieldName, cell)
 			}
 		} else {
 			// map和slice格式的配置数据
+			// 多行格式的续行只检查有值的单元格,#Required在第一行检查
+			isContinuation := isMultiRow && isMultiRowContinuation(row, multiRowKeyColumns)
 			for _, columnOpt := range opt.ColumnOpts {
 				if exportOption.ExportGroup != "" && !strings.Contains(columnOpt.ExportGroup, exportOption.ExportGroup) {
 					continue
 				}
 				if columnOpt.ColumnIndex >= len(row) {
+					if isContinuation {
+						continue
+					}
+					// 空的cell也需要检查#Required
+					if fieldDesc := FindFieldDescriptor(msgDesc, columnOpt.Name); fieldDesc != nil {
+						cellCtx.Column = columnOpt
+						checker.checkCell(cellCtx, fieldDesc, columnOpt, "", nil)
+					}
 					continue // 跳过空的cell
 				}
 				fieldDesc := FindFieldDescriptor(msgDesc, columnOpt.Name)
+				cell := strings.TrimSpace(row[columnOpt.ColumnIndex]) // 移除首尾的空字符串
+				if fieldDesc == nil && findOneOfDescriptor(msgDesc, columnOpt.Name) != nil {
+					oneOfKinds[columnOpt.Name] = cell
+					continue
+				}
 				if fieldDesc == nil {
 					fieldNameNotFoundMap[columnOpt.Name] = struct{}{}
 					//fmt.Println(fmt.Sprintf("FieldNameNotFound %v row%v name:%s sheet:%v", opt.ExcelName, rowIdx, columnOpt.Name, opt.SheetName))
 					continue
 				}
-				cell := strings.TrimSpace(row[columnOpt.ColumnIndex]) // 移除首尾的空字符串
+				cellCtx.Column = columnOpt
 				// format扩展 json
 				if columnOpt.Format == "json" {
-					err = SetFieldValueJson(rowValue, fieldDesc, columnOpt, cell)
+					err = setFieldValueJson(cellCtx, rowValue, fieldDesc, columnOpt, cell)
 					if err != nil {
-						color.Red("SetFieldValueJsonErr row%v sheet:%v err:%v", rowIdx, opt.SheetName, err)
+						cellCtx.Errorf("SetFieldValueJsonErr err:%v", err)
 						continue
 					}
 				} else {
-					err = SetFieldValue(rowValue, fieldDesc, columnOpt, cell, false)
+					err = setFieldValue(cellCtx, rowValue, fieldDesc, columnOpt, cell, false)
 					if err != nil {
-						color.Red("SetFieldValueErr row%v sheet:%v err:%v", rowIdx, opt.SheetName, err)
+						cellCtx.Errorf("SetFieldValueErr err:%v", err)
 						continue
 					}
 				}
+				// 数据校验
+				if cell != "" || !isContinuation {
+					checker.checkCell(cellCtx, fieldDesc, columnOpt, cell, rowValue[getColumnValueKey(fieldDesc, columnOpt)])
+				}
+			}
+			if isContinuation {
+				if lastRowValue == nil {
+					cellCtx.Column = multiRowKeyColumns[0]
+					cellCtx.Errorf("continuation row has no previous row")
+					continue
+				}
+				mergeExpandedSubField(opt, rowValue)
+				rowValue = mergeRepeatedFields(rowValue, opt.ColumnOpts)
+				applyOneOfs(cellCtx, msgDesc, opt.ColumnOpts, oneOfKinds, rowValue)
+				mergeContinuationRow(cellCtx, msgDesc, opt.ColumnOpts, lastRowValue, rowValue)
+				checkOneOfFields(cellCtx, msgDesc, opt.ColumnOpts, "", lastRowValue)
+				continue
 			}
 		}
-		if opt.MgrType == "map" {
-			keyValue := rowValue[opt.MapKeyName]
+		if isKeyedMgrType(opt.MgrType) {
+			var keyValue any
+			if compositeKeyFieldDescs != nil {
+				compositeKey, missingIndex := getCompositeKeyValue(rowValue, opt.MapKeyName)
+				if missingIndex >= 0 {
+					cellCtx.Column = findColumnOptionByField(msgDesc, opt.ColumnOpts, compositeKeyFieldDescs[missingIndex])
+					cellCtx.Errorf("key %s not found", compositeKeyFieldDescs[missingIndex].GetJSONName())
+					lastRowValue = nil
+					continue
+				}
+				keyValue = compositeKey
+			} else {
+				keyValue = rowValue[opt.MapKeyName]
+			}
 			if keyValue == nil {
-				color.Red("%v row%v sheet:%v key %s not found", opt.ExcelName, rowIdx, opt.SheetName, opt.MapKeyName)
-				fmt.Println(fmt.Sprintf("row: %v", rowValue))
+				cellCtx.Column = findColumnOptionByField(msgDesc, opt.ColumnOpts, mapKeyFieldDesc)
+				cellCtx.Errorf("key %s not found", opt.MapKeyName)
+				lastRowValue = nil
 				continue
 			}
 			mergeExpandedSubField(opt, rowValue)
 			rowValue = mergeRepeatedFields(rowValue, opt.ColumnOpts)
+			applyOneOfs(cellCtx, msgDesc, opt.ColumnOpts, oneOfKinds, rowValue)
+			lastRowValue = rowValue
+			checker.checkUniqueKeys(cellCtx, msgDesc, opt.ColumnOpts, rowValue)
+			keyStr, _ := refValueString(keyValue)
+			if opt.MgrType == "group" {
+				// 相同key的行按顺序组成一个数组
+				groupRows, _ := m[keyValue].([]any)
+				m[keyValue] = append(groupRows, rowValue)
+				opt.RowIndexes[keyStr] = append(opt.RowIndexes[keyStr], rowIdx)
+				continue
+			}
+			// 重复的key,后面的行会覆盖前面的行
+			cellCtx.Column = findColumnOptionByField(msgDesc, opt.ColumnOpts, mapKeyFieldDesc)
+			if previousCell, ok := opt.KeyCells[keyStr]; ok {
+				cellCtx.addWithCode(DiagnosticCodeDuplicateKey, getDuplicateKeySeverity(exportOption), "%v %v, previous at %v", duplicateKeyMessage, keyValue, previousCell)
+			}
+			opt.KeyCells[keyStr] = cellCtx.CellName()
+			opt.RowIndexes[keyStr] = []int{rowIdx}
 			m[keyValue] = rowValue
 		} else if opt.MgrType == "slice" {
 			mergeExpandedSubField(opt, rowValue)
 			rowValue = mergeRepeatedFields(rowValue, opt.ColumnOpts)
+			applyOneOfs(cellCtx, msgDesc, opt.ColumnOpts, oneOfKinds, rowValue)
+			lastRowValue = rowValue
+			checker.checkUniqueKeys(cellCtx, msgDesc, opt.ColumnOpts, rowValue)
+			opt.RowIndexes[strconv.Itoa(len(s))] = []int{rowIdx}
 			s = append(s, rowValue)
 		}
 	}
+	if opt.MgrType == "object" {
+		checkRequiredFieldColumns(msgDesc, opt, objectColumns, diags)
+	} else if len(opt.ColumnOpts) > 0 {
+		checkRequiredFieldColumns(msgDesc, opt, opt.ColumnOpts, diags)
+	}
+	if len(checker.uniqueValues) > 0 {
+		opt.UniqueCells = checker.uniqueValues
+	}
 	if len(fieldNameNotFoundMap) > 0 {
 		var fieldNames []string
 		for k, _ := range fieldNameNotFoundMap {
 			fieldNames = append(fieldNames, k)
 		}
-		color.Yellow("FieldNameNotFound %v %v %v", opt.ExcelName, opt.SheetName, fieldNames)
+		sort.Strings(fieldNames)
+		diags.Warnf(opt.ExcelName, opt.SheetName, "FieldNameNotFound %v", fieldNames)
 	}
-	if opt.MgrType == "map" {
+	if isKeyedMgrType(opt.MgrType) {
 		// 把key转换成实际类型
-		return convertToJsonMapByKeyType(m, opt.MapKeyType), nil
+		return convertToJsonMapByKeyType(m, opt.MapKeyType), opt, nil
 	} else if opt.MgrType == "slice" {
-		return s, nil
+		return s, opt, nil
 	} else if opt.MgrType == "object" {
-		if opt.MgrType == "object" {
-			m = mergeExpandedSubFieldOfObject(m)
-		}
-		return convertToJsonMapByKeyType(m, "string"), nil
+		m = mergeExpandedSubFieldOfObject(m)
+		m = applyOneOfsOfObject(diags, opt, msgDesc, objectOneOfKinds, m)
+		return convertToJsonMapByKeyType(m, "string"), opt, nil
 	}
-	return nil, errors.New(fmt.Sprintf("unsupported MgrType %v sheet:%v", opt.MgrType, opt.SheetName))
+	return nil, nil, errors.New(fmt.Sprintf("unsupported MgrType %v sheet:%v", opt.MgrType, opt.SheetName))
 }
 
-// 把展开的子字段合并
+// 把展开的子字段合并,支持多层展开,如Base.Pos.X合并到Base:{Pos:{X:1}}
+// 带下标的列合并到数组中,如Rewards[0].CfgId合并到Rewards:[{CfgId:1}],所有列都为空的元素跳过
 func mergeExpandedSubField(opt *SheetOption, rowValue map[string]any) map[string]any {
 	hasExpandSubField := false
+	hasIndexed := false
 	for _, columnOpt := range opt.ColumnOpts {
 		if columnOpt.IsExpand() {
 			hasExpandSubField = true
-			break
+		}
+		if columnOpt.Indexed {
+			hasIndexed = true
 		}
 	}
 	if !hasExpandSubField {
 		return rowValue
 	}
-	// 合并展开的子字段
+	// 合并展开的子字段,没有值的子对象导出为空对象,多层展开时只创建第一层
 	for _, columnOpt := range opt.ColumnOpts {
-		if columnOpt.IsExpand() {
-			rowValue[columnOpt.ExpandName] = make(map[string]any) // 子对象
+		if columnOpt.IsExpand() && !columnOpt.Indexed {
+			rootName, _, _ := strings.Cut(columnOpt.ExpandName, ".")
+			if _, ok := rowValue[rootName].(map[string]any); !ok {
+				rowValue[rootName] = make(map[string]any) // 子对象
+			}
 		}
 	}
 	for _, columnOpt := range opt.ColumnOpts {
 		if columnOpt.IsExpand() {
-			childValue := rowValue[columnOpt.ExpandName].(map[string]any)
 			if fieldValue, ok := rowValue[columnOpt.Name]; ok {
-				childValue[columnOpt.ExpandFieldName] = fieldValue // 子对象字段赋值
-				delete(rowValue, columnOpt.Name)                   // 子对象字段赋值完,删除
+				delete(rowValue, columnOpt.Name) // 子对象字段赋值完,删除
+				if segments, ok := parseColumnPath(columnOpt.Name); ok {
+					setColumnPathValue(rowValue, segments, fieldValue) // 子对象字段赋值
+				}
 			}
 		}
 	}
+	if hasIndexed {
+		finishIndexedList(rowValue)
+	}
 	return rowValue
 }
 
@@ -440,22 +723,31 @@ func mergeRepeatedFields(rowValue map[string]any, columnOpts []*ColumnOption) ma
 			mergeFieldMap[fieldName] = append(mergeFieldMap[fieldName], value)
 			delete(rowValue, columnOpt.MergeKey)
 			hasMergeData = true
-			fmt.Println(fmt.Sprintf("delete MergeKey: %v value:%v", columnOpt.MergeKey, value))
-			fmt.Println(rowValue)
 		}
 	}
 	if hasMergeData {
-		fmt.Println(mergeFieldMap)
 		for fieldName, values := range mergeFieldMap {
 			if len(values) > 0 {
 				rowValue[fieldName] = values
 			}
 		}
-		fmt.Println(rowValue)
 	}
 	return rowValue
 }
 
+// 查找字段对应的列
+func findColumnOptionByField(msgDesc *desc.MessageDescriptor, columnOpts []*ColumnOption, fieldDesc *desc.FieldDescriptor) *ColumnOption {
+	if fieldDesc == nil {
+		return nil
+	}
+	for _, columnOpt := range columnOpts {
+		if FindFieldDescriptor(msgDesc, columnOpt.Name) == fieldDesc {
+			return columnOpt
+		}
+	}
+	return nil
+}
+
 func hasMergeColumn(columnOpts []*ColumnOption) bool {
 	for _, columnOpt := range columnOpts {
 		if columnOpt.Merge {
@@ -472,13 +764,7 @@ func mergeExpandedSubFieldOfObject(m map[any]any) map[any]any {
 	for k, _ := range m {
 		fieldName := k.(string)
 		columnOpt := &ColumnOption{Name: fieldName}
-		if strings.Index(fieldName, ".") > 0 {
-			expandNames := strings.Split(fieldName, ".")
-			if len(expandNames) == 2 {
-				columnOpt.ExpandName = expandNames[0]
-				columnOpt.ExpandFieldName = expandNames[1]
-			}
-		}
+		columnOpt.parseExpandName()
 		opt.ColumnOpts = append(opt.ColumnOpts, columnOpt)
 		if columnOpt.IsExpand() {
 			hasExpandSubField = true
@@ -487,22 +773,16 @@ func mergeExpandedSubFieldOfObject(m map[any]any) map[any]any {
 	if !hasExpandSubField {
 		return m
 	}
-	// 合并展开的子字段
-	for _, columnOpt := range opt.ColumnOpts {
-		if columnOpt.IsExpand() {
-			m[columnOpt.ExpandName] = make(map[string]any) // 子对象
-		}
+	rowValue := make(map[string]any, len(m))
+	for k, v := range m {
+		rowValue[k.(string)] = v
 	}
-	for _, columnOpt := range opt.ColumnOpts {
-		if columnOpt.IsExpand() {
-			childValue := m[columnOpt.ExpandName].(map[string]any)
-			if fieldValue, ok := m[columnOpt.Name]; ok {
-				childValue[columnOpt.ExpandFieldName] = fieldValue // 子对象字段赋值
-				delete(m, columnOpt.Name)                          // 子对象字段赋值完,删除
-			}
-		}
+	rowValue = mergeExpandedSubField(opt, rowValue)
+	result := make(map[any]any, len(rowValue))
+	for k, v := range rowValue {
+		result[k] = v
 	}
-	return m
+	return result
 }
 
 func isColumnNameDefineRow(column0 string) bool {
@@ -567,7 +847,12 @@ func convertToJsonMapByKeyType(m map[any]any, keyType string) any {
 	return m
 }
 
+// 转换过程中的问题直接输出到控制台
 func SetFieldValue(m map[string]any, fieldDesc *desc.FieldDescriptor, opt *ColumnOption, cellValue string, isSubMsg bool) error {
+	return setFieldValue(nil, m, fieldDesc, opt, cellValue, isSubMsg)
+}
+
+func setFieldValue(ctx *CellContext, m map[string]any, fieldDesc *desc.FieldDescriptor, opt *ColumnOption, cellValue string, isSubMsg bool) error {
 	var fieldValue any
 	// [] or map
 	if fieldDesc.IsRepeated() {
@@ -590,17 +875,17 @@ func SetFieldValue(m map[string]any, fieldDesc *desc.FieldDescriptor, opt *Colum
 					if len(kv) != 2 {
 						continue
 					}
-					k := ConvertFieldValue(keyType, opt, kv[0])
-					v := ConvertFieldValue(valueType, opt, kv[1])
+					k := convertFieldValue(ctx, keyType, opt, kv[0])
+					v := convertFieldValue(ctx, valueType, opt, kv[1])
 					mapField[k] = v
 				}
 			}
 			if len(mapField) > 0 {
 				fieldValue = convertToJsonMapByKeyType(mapField, GetKeyTypeString(keyType))
 			}
-		} else if opt.Merge {
-			// repeated字段 + #Merge标记: 解析为单个元素,后续合并
-			elem := ConvertFieldValue(fieldDesc, opt, cellValue)
+		} else if opt.Merge || (opt.IsIndexedElem() && !isSubMsg) {
+			// repeated字段 + #Merge标记或者带下标的列(如NextQuests[1]): 解析为单个元素,后续合并
+			elem := convertFieldValue(ctx, fieldDesc, opt, cellValue)
 			if elem != nil {
 				fieldValue = elem
 			}
@@ -616,7 +901,7 @@ func SetFieldValue(m map[string]any, fieldDesc *desc.FieldDescriptor, opt *Colum
 				}
 				elemValues := strings.Split(line, sepChar)
 				for _, elemValue := range elemValues {
-					elem := ConvertFieldValue(fieldDesc, opt, elemValue)
+					elem := convertFieldValue(ctx, fieldDesc, opt, elemValue)
 					if elem != nil {
 						repeatedElems = append(repeatedElems, elem)
 					}
@@ -628,14 +913,13 @@ func SetFieldValue(m map[string]any, fieldDesc *desc.FieldDescriptor, opt *Colum
 		}
 	} else {
 		// 普通字段
-		fieldValue = ConvertFieldValue(fieldDesc, opt, cellValue)
+		fieldValue = convertFieldValue(ctx, fieldDesc, opt, cellValue)
 	}
 	if fieldValue == nil {
 		return nil
 	}
 	if opt.Merge && !isSubMsg {
 		m[opt.MergeKey] = fieldValue
-		fmt.Println(fmt.Sprintf("SetFieldValueMerge %v:%v", opt.MergeKey, fieldValue))
 	} else if opt.IsExpand() && !isSubMsg {
 		// 子字段展开(如Obj.SkillIds)仅在最外层rowValue生效,由后续mergeExpandedSubField合并到Obj子对象;
 		// 递归进入嵌套message后(isSubMsg=true)必须用字段自身的JSON名写入,否则会用外层expand的Name
@@ -648,7 +932,12 @@ func SetFieldValue(m map[string]any, fieldDesc *desc.FieldDescriptor, opt *Colum
 	return nil
 }
 
+// 转换过程中的问题直接输出到控制台
 func SetFieldValueJson(m map[string]any, fieldDesc *desc.FieldDescriptor, opt *ColumnOption, cellValue string) error {
+	return setFieldValueJson(nil, m, fieldDesc, opt, cellValue)
+}
+
+func setFieldValueJson(ctx *CellContext, m map[string]any, fieldDesc *desc.FieldDescriptor, opt *ColumnOption, cellValue string) error {
 	if len(cellValue) == 0 {
 		return nil
 	}
@@ -662,8 +951,8 @@ func SetFieldValueJson(m map[string]any, fieldDesc *desc.FieldDescriptor, opt *C
 			if len(cellValue) > 0 && cellValue[0] != '{' && cellValue[len(cellValue)-1] != '}' {
 				cellValue = "{" + cellValue + "}"
 			}
-		} else if opt.Merge {
-			// repeated字段 + #Merge标记: 解析为单个元素,后续合并
+		} else if opt.Merge || opt.IsIndexedElem() {
+			// repeated字段 + #Merge标记或者带下标的列: 解析为单个元素,后续合并
 			jsonValue = make(map[string]any)
 			if len(cellValue) > 0 && cellValue[0] != '{' && cellValue[len(cellValue)-1] != '}' {
 				cellValue = "{" + cellValue + "}"
@@ -681,8 +970,6 @@ func SetFieldValueJson(m map[string]any, fieldDesc *desc.FieldDescriptor, opt *C
 	}
 	err := json.Unmarshal([]byte(cellValue), &jsonValue)
 	if err != nil {
-		color.Red("SetFieldValueJsonErr err:%v", err)
-		fmt.Println(cellValue)
 		return err
 	}
 	if opt.Merge {
@@ -692,7 +979,6 @@ func SetFieldValueJson(m map[string]any, fieldDesc *desc.FieldDescriptor, opt *C
 	} else {
 		m[fieldDesc.GetJSONName()] = jsonValue
 	}
-	fmt.Println(fmt.Sprintf("SetFieldValueJson %v:%v", fieldDesc.GetJSONName(), jsonValue))
 	return nil
 }
 
@@ -723,7 +1009,13 @@ func IsDigit(cellValue string) bool {
 	return true
 }
 
+// 转换过程中的问题直接输出到控制台
 func ConvertFieldValue(fieldDesc *desc.FieldDescriptor, columnOption *ColumnOption, cellValue string) any {
+	return convertFieldValue(nil, fieldDesc, columnOption, cellValue)
+}
+
+// ctx用于定位诊断信息,为nil时直接输出到控制台
+func convertFieldValue(ctx *CellContext, fieldDesc *desc.FieldDescriptor, columnOption *ColumnOption, cellValue string) any {
 	if len(cellValue) == 0 {
 		return nil
 	}
@@ -745,52 +1037,141 @@ func ConvertFieldValue(fieldDesc *desc.FieldDescriptor, columnOption *ColumnOpti
 	case descriptorpb.FieldDescriptorProto_TYPE_INT32,
 		descriptorpb.FieldDescriptorProto_TYPE_SFIXED32,
 		descriptorpb.FieldDescriptorProto_TYPE_SINT32:
-		fieldValue = int32(Atoi(cellValue))
+		i, err := ParseIntCell(cellValue, 32)
+		if err != nil {
+			if i, err = parseTimeIntCell(ctx, columnOption, cellValue, err); err == nil && (i < math.MinInt32 || i > math.MaxInt32) {
+				ctx.Errorf("field:%v time value %v out of int32 range", fieldDesc.GetName(), i)
+				break
+			}
+		}
+		if err != nil {
+			ctx.parseFailed("field:%v %v", fieldDesc.GetName(), err)
+			if ctx.IsStrict() {
+				break
+			}
+			i = int64(Atoi(cellValue))
+		}
+		fieldValue = int32(i)
 
 	case descriptorpb.FieldDescriptorProto_TYPE_INT64,
 		descriptorpb.FieldDescriptorProto_TYPE_SFIXED64,
 		descriptorpb.FieldDescriptorProto_TYPE_SINT64:
-		fieldValue = int64(Atoi(cellValue))
+		i, err := ParseIntCell(cellValue, 64)
+		if err != nil {
+			i, err = parseTimeIntCell(ctx, columnOption, cellValue, err)
+		}
+		if err != nil {
+			ctx.parseFailed("field:%v %v", fieldDesc.GetName(), err)
+			if ctx.IsStrict() {
+				break
+			}
+			i = int64(Atoi(cellValue))
+		}
+		fieldValue = i
 
 	case descriptorpb.FieldDescriptorProto_TYPE_UINT32, descriptorpb.FieldDescriptorProto_TYPE_FIXED32:
-		fieldValue = uint32(Atou(cellValue))
+		u, err := ParseUintCell(cellValue, 32)
+		if err != nil {
+			var i int64
+			if i, err = parseTimeIntCell(ctx, columnOption, cellValue, err); err == nil && (i < 0 || i > math.MaxUint32) {
+				ctx.Errorf("field:%v time value %v out of uint32 range", fieldDesc.GetName(), i)
+				break
+			}
+			u = uint64(i)
+		}
+		if err != nil {
+			ctx.parseFailed("field:%v %v", fieldDesc.GetName(), err)
+			if ctx.IsStrict() {
+				break
+			}
+			u = Atou(cellValue)
+		}
+		fieldValue = uint32(u)
 
 	case descriptorpb.FieldDescriptorProto_TYPE_UINT64, descriptorpb.FieldDescriptorProto_TYPE_FIXED64:
-		fieldValue = uint64(Atou(cellValue))
+		u, err := ParseUintCell(cellValue, 64)
+		if err != nil {
+			var i int64
+			if i, err = parseTimeIntCell(ctx, columnOption, cellValue, err); err == nil && i < 0 {
+				ctx.Errorf("field:%v time value %v out of uint64 range", fieldDesc.GetName(), i)
+				break
+			}
+			u = uint64(i)
+		}
+		if err != nil {
+			ctx.parseFailed("field:%v %v", fieldDesc.GetName(), err)
+			if ctx.IsStrict() {
+				break
+			}
+			u = Atou(cellValue)
+		}
+		fieldValue = u
 
 	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT:
-		f, _ := strconv.ParseFloat(cellValue, 32)
+		f, err := strconv.ParseFloat(cellValue, 32)
+		if err != nil {
+			ctx.parseFailed("field:%v invalid float value %q", fieldDesc.GetName(), cellValue)
+			if ctx.IsStrict() {
+				break
+			}
+		}
 		fieldValue = float32(f)
 
 	case descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:
-		f, _ := strconv.ParseFloat(cellValue, 64)
+		f, err := strconv.ParseFloat(cellValue, 64)
+		if err != nil {
+			ctx.parseFailed("field:%v invalid double value %q", fieldDesc.GetName(), cellValue)
+			if ctx.IsStrict() {
+				break
+			}
+		}
 		fieldValue = f
 
 	case descriptorpb.FieldDescriptorProto_TYPE_STRING:
 		fieldValue = cellValue
 
 	case descriptorpb.FieldDescriptorProto_TYPE_BOOL:
-		fieldValue = strings.ToLower(cellValue) == "true" || cellValue == "1"
+		b, err := ParseBoolCell(cellValue)
+		if err != nil {
+			ctx.parseFailed("field:%v %v", fieldDesc.GetName(), err)
+			if ctx.IsStrict() {
+				break
+			}
+		}
+		fieldValue = b
 
 	case descriptorpb.FieldDescriptorProto_TYPE_ENUM:
 		if IsDigit(cellValue) {
-			fieldValue = Atoi(cellValue)
+			i, err := ParseIntCell(cellValue, 32)
+			if err != nil {
+				ctx.parseFailed("field:%v %v", fieldDesc.GetName(), err)
+				if ctx.IsStrict() {
+					break
+				}
+				i = int64(Atoi(cellValue))
+			}
+			fieldValue = int32(i)
 		} else {
 			// 枚举转数字
 			enumDesc := fieldDesc.GetEnumType()
 			if enumDesc == nil {
-				color.Red("GetEnumType error %v %v", fieldDesc.GetName(), cellValue)
+				ctx.Errorf("GetEnumType error %v %v", fieldDesc.GetName(), cellValue)
 				break
 			}
 			enumValueDesc := enumDesc.FindValueByName(cellValue)
 			if enumValueDesc == nil {
-				color.Red("convert enum error %v %v", fieldDesc.GetName(), cellValue)
+				ctx.Errorf("convert enum error field:%v enum:%v value:%q", fieldDesc.GetName(), enumDesc.GetName(), cellValue)
 				break
 			}
 			fieldValue = enumValueDesc.GetNumber()
 		}
 
 	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE:
+		// Timestamp Duration等well-known types
+		if value, ok := convertWellKnownValue(ctx, fieldDesc, columnOption, cellValue); ok {
+			fieldValue = value
+			break
+		}
 		// 嵌套结构,递归解析
 		subMsgValue := make(map[string]any)
 		subMsgDesc := fieldDesc.GetMessageType()
@@ -806,6 +1187,7 @@ func ConvertFieldValue(fieldDesc *desc.FieldDescriptor, columnOption *ColumnOpti
 				Ref:             columnOption.Ref,
 				ExpandName:      columnOption.ExpandName,
 				ExpandFieldName: columnOption.ExpandFieldName,
+				Indexed:         columnOption.Indexed,
 				Merge:           columnOption.Merge,
 				MergeKey:        columnOption.MergeKey,
 			}
@@ -827,7 +1209,7 @@ func ConvertFieldValue(fieldDesc *desc.FieldDescriptor, columnOption *ColumnOpti
 					//   repeated ItemNumList Items = 1;
 					// }
 					isRepeatedSingleFieldList = true
-					SetFieldValue(subMsgValue, subFieldDesc, subOpt, cellValue, true)
+					setFieldValue(ctx, subMsgValue, subFieldDesc, subOpt, cellValue, true)
 				}
 			}
 			if !isRepeatedSingleFieldList {
@@ -853,7 +1235,7 @@ func ConvertFieldValue(fieldDesc *desc.FieldDescriptor, columnOption *ColumnOpti
 						break
 					}
 					subFieldDesc := subMsgDesc.GetFields()[fieldIndex]
-					SetFieldValue(subMsgValue, subFieldDesc, subOpt, fieldStr, true)
+					setFieldValue(ctx, subMsgValue, subFieldDesc, subOpt, fieldStr, true)
 				}
 			}
 		} else if columnOption.IsFullFieldName() {
@@ -862,10 +1244,10 @@ func ConvertFieldValue(fieldDesc *desc.FieldDescriptor, columnOption *ColumnOpti
 			for _, kv := range kvs {
 				subFieldDesc := FindFieldDescriptor(subMsgDesc, kv.Key)
 				if subFieldDesc == nil {
-					color.Red("field %s not found", kv.Key)
+					ctx.Errorf("field %s not found", kv.Key)
 					continue
 				}
-				SetFieldValue(subMsgValue, subFieldDesc, subOpt, kv.Value, true)
+				setFieldValue(ctx, subMsgValue, subFieldDesc, subOpt, kv.Value, true)
 			}
 		} else {
 			// #Field=Field1_Field2_Field3
@@ -885,10 +1267,10 @@ func ConvertFieldValue(fieldDesc *desc.FieldDescriptor, columnOption *ColumnOpti
 				subFieldName := columnOption.FieldNames[fieldIndex]
 				subFieldDesc := subMsgDesc.FindFieldByName(subFieldName)
 				if subFieldDesc == nil {
-					color.Red("field %v %v not found", fieldIndex, subFieldName)
+					ctx.Errorf("field %v %v not found", fieldIndex, subFieldName)
 					continue
 				}
-				SetFieldValue(subMsgValue, subFieldDesc, subOpt, fieldStr, true)
+				setFieldValue(ctx, subMsgValue, subFieldDesc, subOpt, fieldStr, true)
 			}
 		}
 		if len(subMsgValue) == 0 {
@@ -896,12 +1278,102 @@ func ConvertFieldValue(fieldDesc *desc.FieldDescriptor, columnOption *ColumnOpti
 		}
 		fieldValue = subMsgValue
 
+	case descriptorpb.FieldDescriptorProto_TYPE_BYTES:
+		format := ""
+		if columnOption != nil {
+			format = columnOption.Format
+		}
+		b, err := ParseBytesCell(cellValue, format)
+		if err != nil {
+			ctx.parseFailed("field:%v %v", fieldDesc.GetName(), err)
+			break
+		}
+		// 导出为protojson的bytes格式(base64),json和yaml中也是base64字符串
+		fieldValue = base64.StdEncoding.EncodeToString(b)
+
 	default:
-		color.Red("field type %v not support", fieldDesc.GetType())
+		ctx.Errorf("field type %v not support", fieldDesc.GetType())
 	}
 	return fieldValue
 }
 
+// 解析整数单元格,兼容excel中整数的浮点格式(如100.0)
+func ParseIntCell(cellValue string, bitSize int) (int64, error) {
+	i, err := strconv.ParseInt(trimIntegralFraction(cellValue), 10, bitSize)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("int%v value out of range %q", bitSize, cellValue)
+		}
+		return 0, fmt.Errorf("invalid int%v value %q", bitSize, cellValue)
+	}
+	return i, nil
+}
+
+// 解析无符号整数单元格,兼容excel中整数的浮点格式(如100.0)
+func ParseUintCell(cellValue string, bitSize int) (uint64, error) {
+	if strings.HasPrefix(cellValue, "-") {
+		return 0, fmt.Errorf("negative value in uint%v field %q", bitSize, cellValue)
+	}
+	u, err := strconv.ParseUint(trimIntegralFraction(cellValue), 10, bitSize)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("uint%v value out of range %q", bitSize, cellValue)
+		}
+		return 0, fmt.Errorf("invalid uint%v value %q", bitSize, cellValue)
+	}
+	return u, nil
+}
+
+// 解析bool单元格,支持true false 1 0(不区分大小写)
+func ParseBoolCell(cellValue string) (bool, error) {
+	switch strings.ToLower(cellValue) {
+	case "true", "1":
+		return true, nil
+	case "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid bool value %q", cellValue)
+}
+
+// 解析bytes单元格,#Format=base64:base64编码 #Format=hex:16进制(可以有0x前缀和空格) 其他:utf8文本
+func ParseBytesCell(cellValue, format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "base64":
+		if b, err := base64.StdEncoding.DecodeString(cellValue); err == nil {
+			return b, nil
+		}
+		// 兼容没有填充=的base64
+		b, err := base64.RawStdEncoding.DecodeString(cellValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 value %q", cellValue)
+		}
+		return b, nil
+	case "hex":
+		hexValue := strings.ReplaceAll(cellValue, " ", "")
+		if strings.HasPrefix(hexValue, "0x") || strings.HasPrefix(hexValue, "0X") {
+			hexValue = hexValue[2:]
+		}
+		b, err := hex.DecodeString(hexValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex value %q", cellValue)
+		}
+		return b, nil
+	}
+	return []byte(cellValue), nil
+}
+
+// 去掉整数的浮点格式的小数部分,如100.0 -> 100
+func trimIntegralFraction(cellValue string) string {
+	idx := strings.IndexByte(cellValue, '.')
+	if idx <= 0 {
+		return cellValue
+	}
+	if strings.Trim(cellValue[idx+1:], "0") != "" {
+		return cellValue
+	}
+	return cellValue[:idx]
+}
+
 func Atoi(s string) int {
 	i, err := strconv.Atoi(s)
 	if err != nil {
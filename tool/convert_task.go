@@ -0,0 +1,94 @@
+package tool
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// 一个sheet的转换任务
+type convertTask struct {
+	ExcelFileName string
+	SheetOption   *SheetOption
+	MsgDesc       *desc.MessageDescriptor
+	MergeName     string
+	CodeComment   string
+	ExcelHash     string // excel文件的hash,用于增量导出
+
+	// 转换结果
+	Data         any
+	ResultOption *SheetOption // 解析后的sheet设置
+	Diags        *Diagnostics
+	Err          error
+	Cached       bool // 是否使用了缓存的转换结果
+}
+
+func (t *convertTask) run(exportOption *ExportOption) {
+	t.Diags = NewDiagnostics()
+	f, err := OpenSourceFile(exportOption.DataImportPath + t.ExcelFileName)
+	if err != nil {
+		t.Err = err
+		return
+	}
+	defer f.Close()
+	t.Data, t.ResultOption, t.Err = convertSheet(exportOption, f, t.SheetOption, t.MsgDesc, t.Diags)
+}
+
+// 并发数,默认为cpu数量
+func getConcurrency(exportOption *ExportOption, taskCount int) int {
+	concurrency := exportOption.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	if concurrency > taskCount {
+		concurrency = taskCount
+	}
+	return concurrency
+}
+
+// 并发转换所有的sheet,转换结果保存在task里,已经使用缓存的task不再转换
+func runConvertTasks(exportOption *ExportOption, tasks []*convertTask) {
+	var pendingTasks []*convertTask
+	for _, task := range tasks {
+		if !task.Cached {
+			pendingTasks = append(pendingTasks, task)
+		}
+	}
+	tasks = pendingTasks
+	concurrency := getConcurrency(exportOption, len(tasks))
+	if len(tasks) == 0 {
+		return
+	}
+	if concurrency <= 1 {
+		for _, task := range tasks {
+			task.run(exportOption)
+		}
+		return
+	}
+	taskChan := make(chan *convertTask)
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range taskChan {
+				task.run(exportOption)
+			}
+		}()
+	}
+	for _, task := range tasks {
+		taskChan <- task
+	}
+	close(taskChan)
+	wg.Wait()
+}
+
+func hasTaskError(tasks []*convertTask) bool {
+	for _, task := range tasks {
+		if task.Err != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package tool
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunConvertTasks(t *testing.T) {
+	initProtoForTest(t)
+	dir := t.TempDir()
+	exportOption := &ExportOption{
+		DataImportPath: dir + "/",
+		Concurrency:    4,
+	}
+	var tasks []*convertTask
+	for i := 0; i < 8; i++ {
+		excelFileName := fmt.Sprintf("level%v.xlsx", i)
+		f := newTestSheetFile(t, "LevelExp",
+			[]interface{}{"Level", "NeedExp"},
+			[]interface{}{fmt.Sprintf("%v", i), fmt.Sprintf("%v", i*100)},
+		)
+		if err := f.SaveAs(filepath.Join(dir, excelFileName)); err != nil {
+			t.Fatal(err)
+		}
+		_ = f.Close()
+		sheetOption := &SheetOption{
+			ExcelName:   excelFileName,
+			SheetName:   "LevelExp",
+			MessageName: "LevelExp",
+			MgrType:     "map",
+		}
+		tasks = append(tasks, &convertTask{
+			ExcelFileName: excelFileName,
+			SheetOption:   sheetOption,
+			MsgDesc:       FindMessageDescriptor(sheetOption.MessageName),
+		})
+	}
+	runConvertTasks(exportOption, tasks)
+	for i, task := range tasks {
+		if task.Err != nil {
+			t.Fatal(task.Err)
+		}
+		m := task.Data.(map[int32]any)
+		if _, ok := m[int32(i)]; !ok || len(m) != 1 {
+			t.Errorf("task%v unexpected data:%v", i, m)
+		}
+		// 传入的SheetOption不会被修改
+		if task.SheetOption.MapKeyName != "" || task.SheetOption.ColumnOpts != nil {
+			t.Errorf("task%v SheetOption modified:%+v", i, task.SheetOption)
+		}
+		if task.ResultOption.MapKeyName != "Level" || task.ResultOption.MapKeyType != "int32" || len(task.ResultOption.ColumnOpts) != 2 {
+			t.Errorf("task%v unexpected ResultOption:%+v", i, task.ResultOption)
+		}
+	}
+}
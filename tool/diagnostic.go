@@ -0,0 +1,266 @@
+package tool
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/xuri/excelize/v2"
+)
+
+// 诊断信息的严重等级
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "WARN"
+	case SeverityError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// 诊断信息的类型,用于按类型筛选诊断信息,不依赖Message的内容
+type DiagnosticCode string
+
+const (
+	DiagnosticCodeNone         DiagnosticCode = ""              // 没有特定类型
+	DiagnosticCodeDuplicateKey DiagnosticCode = "duplicate_key" // map格式的配置表中重复的key,包括合并的sheet
+)
+
+// 导出过程中的一条诊断信息
+type Diagnostic struct {
+	Severity  Severity
+	Code      DiagnosticCode
+	ExcelName string
+	SheetName string
+	Cell      string // A1格式的单元格坐标,为空表示整个sheet或整列
+	Column    string // 列名
+	Message   string
+}
+
+func (d *Diagnostic) String() string {
+	sb := strings.Builder{}
+	sb.WriteString("[")
+	sb.WriteString(d.Severity.String())
+	sb.WriteString("]")
+	if d.ExcelName != "" {
+		sb.WriteString(" ")
+		sb.WriteString(d.ExcelName)
+	}
+	if d.SheetName != "" {
+		sb.WriteString(" ")
+		sb.WriteString(d.SheetName)
+		if d.Cell != "" {
+			sb.WriteString("!")
+			sb.WriteString(d.Cell)
+		}
+	}
+	if d.Column != "" {
+		sb.WriteString(" column:")
+		sb.WriteString(d.Column)
+	}
+	sb.WriteString(" ")
+	sb.WriteString(d.Message)
+	return sb.String()
+}
+
+// 诊断信息收集器,并发安全
+// nil的*Diagnostics也可以使用,此时直接输出到控制台
+type Diagnostics struct {
+	mu    sync.Mutex
+	items []*Diagnostic
+}
+
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{}
+}
+
+func (d *Diagnostics) Add(diag *Diagnostic) {
+	if d == nil {
+		printDiagnostic(diag)
+		return
+	}
+	d.mu.Lock()
+	d.items = append(d.items, diag)
+	d.mu.Unlock()
+}
+
+// 添加一个sheet级别的错误
+func (d *Diagnostics) Errorf(excelName, sheetName, format string, args ...any) {
+	d.Add(&Diagnostic{
+		Severity:  SeverityError,
+		ExcelName: excelName,
+		SheetName: sheetName,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+// 添加一个sheet级别的警告
+func (d *Diagnostics) Warnf(excelName, sheetName, format string, args ...any) {
+	d.Add(&Diagnostic{
+		Severity:  SeverityWarning,
+		ExcelName: excelName,
+		SheetName: sheetName,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+// 合并另一个收集器的诊断信息
+func (d *Diagnostics) Append(other *Diagnostics) {
+	if d == nil || other == nil || d == other {
+		return
+	}
+	for _, item := range other.Items() {
+		d.Add(item)
+	}
+}
+
+// 所有诊断信息的拷贝
+func (d *Diagnostics) Items() []*Diagnostic {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	items := make([]*Diagnostic, len(d.items))
+	copy(items, d.items)
+	return items
+}
+
+// 返回满足条件的诊断信息
+func (d *Diagnostics) Filter(fn func(diag *Diagnostic) bool) *Diagnostics {
+	result := NewDiagnostics()
+	for _, item := range d.Items() {
+		if fn(item) {
+			result.Add(item)
+		}
+	}
+	return result
+}
+
+func (d *Diagnostics) Count(severity Severity) int {
+	count := 0
+	for _, item := range d.Items() {
+		if item.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+func (d *Diagnostics) HasError() bool {
+	return d.Count(SeverityError) > 0
+}
+
+// 按excel,sheet输出所有诊断信息
+func (d *Diagnostics) Print() {
+	items := d.Items()
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].ExcelName != items[j].ExcelName {
+			return items[i].ExcelName < items[j].ExcelName
+		}
+		return items[i].SheetName < items[j].SheetName
+	})
+	for _, item := range items {
+		printDiagnostic(item)
+	}
+}
+
+func printDiagnostic(diag *Diagnostic) {
+	if diag.Severity == SeverityError {
+		color.Red("%v", diag)
+	} else {
+		color.Yellow("%v", diag)
+	}
+}
+
+// 单元格转换上下文,用于定位诊断信息
+// nil的*CellContext也可以使用,此时直接输出到控制台
+type CellContext struct {
+	Diags     *Diagnostics
+	ExcelName string
+	SheetName string
+	RowIndex  int // 从0开始的行号
+	Column    *ColumnOption
+	Strict    bool           // 严格解析模式
+	Vertical  bool           // 竖表,RowIndex是列号,ColumnIndex是行号
+	Location  *time.Location // Timestamp字段的时区,为空时使用本地时区
+}
+
+func (c *CellContext) getLocation() *time.Location {
+	if c == nil || c.Location == nil {
+		return time.Local
+	}
+	return c.Location
+}
+
+// A1格式的单元格坐标
+func (c *CellContext) CellName() string {
+	if c == nil || c.Column == nil {
+		return ""
+	}
+	col, row := c.Column.ColumnIndex+1, c.RowIndex+1
+	if c.Vertical {
+		col, row = row, col
+	}
+	cellName, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil {
+		return ""
+	}
+	return cellName
+}
+
+func (c *CellContext) add(severity Severity, format string, args ...any) {
+	c.addWithCode(DiagnosticCodeNone, severity, format, args...)
+}
+
+func (c *CellContext) addWithCode(code DiagnosticCode, severity Severity, format string, args ...any) {
+	diag := &Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if c == nil {
+		printDiagnostic(diag)
+		return
+	}
+	diag.ExcelName = c.ExcelName
+	diag.SheetName = c.SheetName
+	diag.Cell = c.CellName()
+	if c.Column != nil {
+		diag.Column = c.Column.Name
+	}
+	c.Diags.Add(diag)
+}
+
+func (c *CellContext) Errorf(format string, args ...any) {
+	c.add(SeverityError, format, args...)
+}
+
+func (c *CellContext) Warnf(format string, args ...any) {
+	c.add(SeverityWarning, format, args...)
+}
+
+// 是否是严格解析模式
+func (c *CellContext) IsStrict() bool {
+	return c != nil && c.Strict
+}
+
+// 单元格值解析失败,严格模式下是错误,否则是警告
+func (c *CellContext) parseFailed(format string, args ...any) {
+	if c.IsStrict() {
+		c.add(SeverityError, format, args...)
+	} else {
+		c.add(SeverityWarning, format, args...)
+	}
+}
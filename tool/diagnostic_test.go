@@ -0,0 +1,83 @@
+package tool
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func newTestSheetFile(t *testing.T, sheetName string, rows ...[]interface{}) *excelize.File {
+	t.Helper()
+	f := excelize.NewFile()
+	idx, err := f.NewSheet(sheetName)
+	if err != nil {
+		t.Fatalf("NewSheet err: %v", err)
+	}
+	f.SetActiveSheet(idx)
+	for i, r := range rows {
+		if err := f.SetSheetRow(sheetName, fmt.Sprintf("A%d", i+1), &r); err != nil {
+			t.Fatalf("SetSheetRow err: %v", err)
+		}
+	}
+	return f
+}
+
+func TestConvertSheetDiagnostics(t *testing.T) {
+	initProtoForTest(t)
+
+	f := newTestSheetFile(t, "LevelExp",
+		[]interface{}{"Level", "NeedExp", "Unknown"},
+		[]interface{}{"1", "100", "x"},
+		[]interface{}{"", "200", "y"},
+	)
+	defer func() { _ = f.Close() }()
+	opt := &SheetOption{
+		ExcelName:   "levelcfg.xlsx",
+		SheetName:   "LevelExp",
+		MessageName: "LevelExp",
+		MgrType:     "map",
+	}
+	diags := NewDiagnostics()
+	result, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), opt, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := result.(map[int32]any); len(m) != 1 {
+		t.Fatalf("expected 1 row, got %v", m)
+	}
+	if diags.Count(SeverityError) != 1 {
+		t.Fatalf("expected 1 error, got %v", diags.Items())
+	}
+	if diags.Count(SeverityWarning) != 1 {
+		t.Fatalf("expected 1 warning, got %v", diags.Items())
+	}
+	for _, item := range diags.Items() {
+		t.Logf("%v", item)
+		if item.ExcelName != "levelcfg.xlsx" || item.SheetName != "LevelExp" {
+			t.Errorf("unexpected location: %v", item)
+		}
+	}
+}
+
+func TestCellContextCellName(t *testing.T) {
+	ctx := &CellContext{
+		RowIndex: 4,
+		Column:   &ColumnOption{Name: "Rewards", ColumnIndex: 2},
+	}
+	if ctx.CellName() != "C5" {
+		t.Errorf("expected C5, got %v", ctx.CellName())
+	}
+	diags := NewDiagnostics()
+	ctx.Diags = diags
+	ctx.Errorf("test %v", 1)
+	items := diags.Items()
+	if len(items) != 1 || items[0].Cell != "C5" || items[0].Column != "Rewards" || items[0].Message != "test 1" {
+		t.Errorf("unexpected diagnostics: %v", items)
+	}
+	var nilCtx *CellContext
+	nilCtx.Warnf("nil context should print only")
+	if !diags.HasError() {
+		t.Error("expected HasError")
+	}
+}
@@ -0,0 +1,77 @@
+package tool
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConvertSheetDuplicateKey(t *testing.T) {
+	initTempProtoForTest(t, "types_test.proto", typesTestProto)
+	f := newTestSheetFile(t, "Types",
+		[]interface{}{"CfgId", "Int64Value"},
+		[]interface{}{"1", "10"},
+		[]interface{}{"2", "20"},
+		[]interface{}{"1", "30"},
+		[]interface{}{"1", "40"},
+	)
+	defer func() { _ = f.Close() }()
+	for _, failOnDuplicateKey := range []bool{false, true} {
+		diags := NewDiagnostics()
+		exportOption := &ExportOption{FailOnDuplicateKey: failOnDuplicateKey}
+		data, resultOpt, err := convertSheet(exportOption, NewExcelSource(f),
+			&SheetOption{ExcelName: "types.xlsx", SheetName: "Types", MessageName: "TestCfgTypes", MgrType: "map"},
+			FindMessageDescriptor("TestCfgTypes"), diags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 后面的行覆盖前面的行
+		if v := data.(map[int32]any)[1].(map[string]any)["Int64Value"]; v != int64(40) {
+			t.Fatalf("unexpected value: %v", v)
+		}
+		wantSeverity := SeverityWarning
+		if failOnDuplicateKey {
+			wantSeverity = SeverityError
+		}
+		var got []string
+		for _, diag := range diags.Items() {
+			if diag.Severity != wantSeverity || diag.Code != DiagnosticCodeDuplicateKey {
+				t.Fatalf("unexpected diag: %v %v", diag, diag.Code)
+			}
+			got = append(got, diag.Cell+" "+diag.Message)
+		}
+		want := []string{
+			"A4 duplicate key 1, previous at A2",
+			"A5 duplicate key 1, previous at A4",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+		}
+		if !reflect.DeepEqual(resultOpt.KeyCells, map[string]string{"1": "A5", "2": "A3"}) {
+			t.Fatalf("unexpected key cells: %v", resultOpt.KeyCells)
+		}
+		if !reflect.DeepEqual(resultOpt.RowIndexes, map[string][]int{"1": {4}, "2": {2}}) {
+			t.Fatalf("unexpected row indexes: %v", resultOpt.RowIndexes)
+		}
+	}
+}
+
+func TestMergeMgrData(t *testing.T) {
+	dst := map[string]any{"a": 1, "b": 2}
+	merged, duplicateKeys, err := mergeMgrData(dst, map[string]any{"c": 3, "b": 4, "a": 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(merged, map[string]any{"a": 5, "b": 4, "c": 3}) {
+		t.Fatalf("unexpected merged data: %v", merged)
+	}
+	if !reflect.DeepEqual(duplicateKeys, []any{"a", "b"}) {
+		t.Fatalf("unexpected duplicate keys: %v", duplicateKeys)
+	}
+	merged, duplicateKeys, err = mergeMgrData([]any{1}, []any{1, 2})
+	if err != nil || len(duplicateKeys) != 0 || !reflect.DeepEqual(merged, []any{1, 1, 2}) {
+		t.Fatalf("unexpected slice merge: %v %v %v", merged, duplicateKeys, err)
+	}
+	if _, _, err = mergeMgrData(map[int32]any{}, map[int64]any{}); err == nil {
+		t.Fatal("expected type mismatch error")
+	}
+}
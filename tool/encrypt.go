@@ -0,0 +1,76 @@
+package tool
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 加密数据格式,和cfg.DecryptData一致:
+//
+//	magic   [4]byte "XENC"
+//	keyId   [4]byte sha256(key)的前4个字节,用于区分key错误和数据被修改
+//	nonce   [12]byte
+//	ciphertext (包含16字节的GCM tag),magic和keyId作为附加数据参与校验
+const encryptMagic = "XENC"
+
+// 加密使用的key,hex格式,长度为16,24,32字节(AES-128,AES-192,AES-256)
+// 配置了EncryptKey时使用EncryptKey,否则从环境变量EncryptKeyEnv读取,都没有配置表示不加密
+func (opt *ExportOption) GetEncryptKey() ([]byte, error) {
+	hexKey := strings.TrimSpace(opt.EncryptKey)
+	if hexKey == "" && opt.EncryptKeyEnv != "" {
+		hexKey = strings.TrimSpace(os.Getenv(opt.EncryptKeyEnv))
+		if hexKey == "" {
+			return nil, fmt.Errorf("encrypt key env %v is empty", opt.EncryptKeyEnv)
+		}
+	}
+	if hexKey == "" {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt key must be hex string:%w", err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("invalid encrypt key length:%v, must be 16, 24 or 32 bytes", len(key))
+}
+
+// 计算nonce的子key,和加密用的key分开
+const encryptNonceInfo = "excelexporter nonce"
+
+// 使用AES-GCM加密
+// nonce是HMAC-SHA256(nonceKey,data)的前12个字节,nonceKey是HKDF(key,"excelexporter nonce")派生的子key
+// 相同的数据加密结果相同(可以看出两份数据是否相同),数据没有变化时md5也不会变化
+// 不同的数据nonce不同,所以不会出现GCM的nonce重用
+func encryptData(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	keyHash := sha256.Sum256(key)
+	nonceKey, err := hkdf.Key(sha256.New, key, nil, encryptNonceInfo, sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, nonceKey)
+	mac.Write(data)
+	nonce := mac.Sum(nil)[:gcm.NonceSize()]
+	additionalData := append([]byte(encryptMagic), keyHash[:4]...)
+	out := make([]byte, 0, len(additionalData)+len(nonce)+len(data)+gcm.Overhead())
+	out = append(out, additionalData...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, additionalData), nil
+}
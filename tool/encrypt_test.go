@@ -0,0 +1,111 @@
+package tool
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"excelexporter/cfg"
+	"excelexporter/example/pb"
+)
+
+func TestGetEncryptKey(t *testing.T) {
+	hexKey := "000102030405060708090a0b0c0d0e0f"
+	key, err := (&ExportOption{EncryptKey: hexKey}).GetEncryptKey()
+	if err != nil || len(key) != 16 {
+		t.Fatalf("key:%v err:%v", key, err)
+	}
+	t.Setenv("EXPORTER_TEST_KEY", hexKey+hexKey)
+	key, err = (&ExportOption{EncryptKeyEnv: "EXPORTER_TEST_KEY"}).GetEncryptKey()
+	if err != nil || len(key) != 32 {
+		t.Fatalf("key:%v err:%v", key, err)
+	}
+	if key, err = (&ExportOption{}).GetEncryptKey(); err != nil || key != nil {
+		t.Fatalf("expected no key, got key:%v err:%v", key, err)
+	}
+	for _, opt := range []*ExportOption{
+		{EncryptKey: "0011"},
+		{EncryptKey: "not hex"},
+		{EncryptKeyEnv: "EXPORTER_TEST_KEY_NOT_SET"},
+	} {
+		if _, err = opt.GetEncryptKey(); err == nil {
+			t.Errorf("expected error for %+v", opt)
+		}
+	}
+}
+
+func TestEncryptData(t *testing.T) {
+	initProtoForTest(t)
+	key := bytes.Repeat([]byte{1}, 32)
+	pbData, err := marshalToProtoBinary(map[int32]any{
+		1: map[string]any{"CfgId": int32(1), "Name": "A"},
+	}, &SheetOption{MessageName: "QuestCfg", MgrType: "map"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := encryptData(key, pbData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.IsEncryptedData(encrypted) || bytes.Contains(encrypted, pbData) {
+		t.Fatal("data not encrypted")
+	}
+	// 相同的数据加密结果相同
+	encrypted2, _ := encryptData(key, pbData)
+	if !bytes.Equal(encrypted, encrypted2) {
+		t.Error("encrypt result not stable")
+	}
+	fileName := filepath.Join(t.TempDir(), "Quests.pb")
+	if err = os.WriteFile(fileName, encrypted, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cfg.SetDecryptKey(nil) }()
+
+	// 没有设置key
+	mgr := cfg.NewDataMap[*pb.QuestCfg]()
+	if err = mgr.LoadPb(fileName); !errors.Is(err, cfg.ErrDecryptKeyNotSet) {
+		t.Errorf("expected ErrDecryptKeyNotSet, got %v", err)
+	}
+	// key错误
+	if err = cfg.SetDecryptKey(bytes.Repeat([]byte{2}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	if err = mgr.LoadPb(fileName); !errors.Is(err, cfg.ErrDecryptWrongKey) {
+		t.Errorf("expected ErrDecryptWrongKey, got %v", err)
+	}
+	// 正确的key
+	if err = cfg.SetDecryptKey(key); err != nil {
+		t.Fatal(err)
+	}
+	if err = mgr.LoadPb(fileName); err != nil {
+		t.Fatal(err)
+	}
+	if mgr.GetCfg(1).GetName() != "A" {
+		t.Errorf("unexpected data")
+	}
+	// 数据被修改
+	for _, idx := range []int{6, 10, len(encrypted) - 1} {
+		tampered := append([]byte(nil), encrypted...)
+		tampered[idx] ^= 0xff
+		_, err = cfg.DecryptData(fileName, tampered)
+		if idx < 8 {
+			if !errors.Is(err, cfg.ErrDecryptWrongKey) {
+				t.Errorf("idx:%v expected ErrDecryptWrongKey, got %v", idx, err)
+			}
+		} else if !errors.Is(err, cfg.ErrDecryptTampered) {
+			t.Errorf("idx:%v expected ErrDecryptTampered, got %v", idx, err)
+		}
+	}
+	// object格式
+	objectData, _ := marshalToProtoBinary(map[string]any{"CfgId": int32(2), "Name": "B"}, &SheetOption{MessageName: "QuestCfg", MgrType: "object"})
+	encrypted, _ = encryptData(key, objectData)
+	if err = os.WriteFile(fileName, encrypted, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	quest := &pb.QuestCfg{}
+	if err = cfg.LoadObjectFromPb(fileName, quest); err != nil || quest.GetName() != "B" {
+		t.Errorf("unexpected object:%v err:%v", quest, err)
+	}
+}
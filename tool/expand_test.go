@@ -0,0 +1,118 @@
+package tool
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const expandTestProto = `syntax = "proto3";
+package expandtest;
+
+message ExpandPos {
+  int32 X = 1;
+  int32 Y = 2;
+}
+
+message ExpandBase {
+  string Name = 1;
+  ExpandPos Pos = 2;
+}
+
+message ExpandCfg {
+  int32 CfgId = 1;
+  ExpandBase Base = 2;
+  map<string,ExpandPos> Points = 3;
+  repeated ExpandPos PosList = 4;
+}
+`
+
+func TestFindFieldDescriptorDeepPath(t *testing.T) {
+	initTempProtoForTest(t, "expand_test.proto", expandTestProto)
+	msgDesc := FindMessageDescriptor("ExpandCfg")
+	tests := []struct {
+		name      string
+		wantField string
+	}{
+		{"Base.Pos.X", "expandtest.ExpandPos.X"},
+		{"Base.Name", "expandtest.ExpandBase.Name"},
+		{"Points.Home", "expandtest.ExpandCfg.PointsEntry.value"},
+		{"Points.Home.Y", "expandtest.ExpandPos.Y"},
+		{"PosList.X", ""}, // repeated字段不能展开
+		{"Base.Pos.Z", ""},
+		{"Base..X", ""},
+	}
+	for _, tt := range tests {
+		fieldDesc := FindFieldDescriptor(msgDesc, tt.name)
+		got := ""
+		if fieldDesc != nil {
+			got = fieldDesc.GetFullyQualifiedName()
+		}
+		if got != tt.wantField {
+			t.Errorf("FindFieldDescriptor(%v)=%v want:%v", tt.name, got, tt.wantField)
+		}
+	}
+	if expandName, fieldName := splitExpandName("Base.Pos.X"); expandName != "Base.Pos" || fieldName != "X" {
+		t.Errorf("unexpected split: %v %v", expandName, fieldName)
+	}
+}
+
+func TestConvertSheetDeepExpand(t *testing.T) {
+	initTempProtoForTest(t, "expand_test.proto", expandTestProto)
+	toJson := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	f := newTestSheetFile(t, "ExpandCfg",
+		[]interface{}{"CfgId", "Base.Name", "Base.Pos.X", "Base.Pos.Y", "Points.Home.X", "Points.Home.Y"},
+		[]interface{}{"1", "a", "1", "2", "3", "4"},
+		[]interface{}{"2", "b", "5"},
+	)
+	defer func() { _ = f.Close() }()
+	for _, mgrType := range []string{"map", "slice"} {
+		diags := NewDiagnostics()
+		opt := &SheetOption{SheetName: "ExpandCfg", MessageName: "ExpandCfg", MgrType: mgrType}
+		data, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), opt, diags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(diags.Items()) > 0 {
+			t.Fatalf("unexpected diags: %v", diags.Items()[0])
+		}
+		want := `[{"Base":{"Name":"a","Pos":{"X":1,"Y":2}},"CfgId":1,"Points":{"Home":{"X":3,"Y":4}}},{"Base":{"Name":"b","Pos":{"X":5}},"CfgId":2,"Points":{}}]`
+		if mgrType == "map" {
+			want = `{"1":{"Base":{"Name":"a","Pos":{"X":1,"Y":2}},"CfgId":1,"Points":{"Home":{"X":3,"Y":4}}},"2":{"Base":{"Name":"b","Pos":{"X":5}},"CfgId":2,"Points":{}}}`
+		}
+		if got := toJson(data); got != want {
+			t.Fatalf("%v got:\n%v\nwant:\n%v", mgrType, got, want)
+		}
+		// 合并后的数据可以转换成proto
+		if _, err = marshalToProtoBinary(data, opt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// object格式
+	f2 := newTestSheetFile(t, "ExpandObj",
+		[]interface{}{"key", "value"},
+		[]interface{}{"CfgId", "1"},
+		[]interface{}{"Base.Pos.X", "7"},
+		[]interface{}{"Base.Name", "obj"},
+		[]interface{}{"Points.Home.Y", "8"},
+	)
+	defer func() { _ = f2.Close() }()
+	opt := &SheetOption{SheetName: "ExpandObj", MessageName: "ExpandCfg", MgrType: "object"}
+	data, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f2), opt, NewDiagnostics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Base":{"Name":"obj","Pos":{"X":7}},"CfgId":1,"Points":{"Home":{"Y":8}}}`
+	if got := toJson(data); got != want {
+		t.Fatalf("object got:\n%v\nwant:\n%v", got, want)
+	}
+	if _, err = marshalToProtoBinary(data, opt); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -41,7 +42,26 @@ type ExportOption struct {
 	ProtoPath  string   `yaml:"ProtoPath"`  // proto所在目录
 	ProtoFiles []string `yaml:"ProtoFiles"` // 需要解析的proto文件
 
-	ExportFormats []string `yaml:"ExportFormats"` // 导出格式: json pb
+	ExportFormats []string          `yaml:"ExportFormats"` // 导出格式: json pb lua yaml prototext bundle
+	BundleFile    string            `yaml:"BundleFile"`    // 打包文件名,默认为cfg.bundle
+	Compress      map[string]string `yaml:"Compress"`      // 可选项:导出格式对应的压缩方式(gzip zstd),如pb: zstd,没有配置表示不压缩
+	EncryptKey    string            `yaml:"EncryptKey"`    // 可选项:pb数据的加密key(hex格式),为空表示不加密
+	EncryptKeyEnv string            `yaml:"EncryptKeyEnv"` // 可选项:EncryptKey为空时,从该环境变量读取加密key
+
+	Concurrency int `yaml:"Concurrency"` // 并发转换sheet的数量,默认为cpu数量
+
+	CacheFile string `yaml:"CacheFile"` // 增量导出的缓存文件,为空表示不使用缓存
+	Force     bool   `yaml:"-"`         // 忽略缓存,强制全部重新导出(命令行参数-force)
+
+	watching       bool   // watch模式,内容没有变化的导出文件不再写入
+	watchCacheData []byte // watch模式下没有设置CacheFile时,保存在内存中的转换缓存
+
+	FailOnError        bool `yaml:"FailOnError"`        // 有错误级别的诊断信息时,导出失败
+	FailOnDuplicateKey bool `yaml:"FailOnDuplicateKey"` // map格式的配置表有重复的key时,作为错误处理,并且导出失败
+	StrictParse        bool `yaml:"StrictParse"`        // 严格解析模式,无法解析的数字,bool,枚举作为错误处理,并且不导出该值
+
+	TimeZone    string `yaml:"TimeZone"`    // 可选项:Timestamp字段和日期单元格的时区,如Asia/Shanghai,为空时使用本地时区
+	CalcFormula bool   `yaml:"CalcFormula"` // 可选项:计算没有缓存值的公式单元格(如程序生成的xlsx文件)
 }
 
 type ExportInfo struct {
@@ -50,21 +70,71 @@ type ExportInfo struct {
 	MergeName   string
 	CodeComment string
 	//ExportFileName string // 导出的文件名
+
+	rowSources map[string][]refRowSource // 每条数据来自哪个sheet的哪一行,用于关联检查定位单元格
+}
+
+var (
+	ErrExportHasError   = errors.New("export has error diagnostics")
+	ErrExportHasDupKeys = errors.New("export has duplicate map keys")
+)
+
+const duplicateKeyMessage = "duplicate key"
+
+// 重复key的诊断级别,默认为警告
+func getDuplicateKeySeverity(exportOption *ExportOption) Severity {
+	if exportOption.FailOnDuplicateKey {
+		return SeverityError
+	}
+	return SeverityWarning
 }
 
 // 从一个总表导出所有的配置表
+// 导出过程中收集的诊断信息在导出结束后输出到控制台
 func ExportAll(exportOption *ExportOption, exportExcelFileName, exportSheetName string) error {
-	checkExportOption(exportOption)
-	f, err := excelize.OpenFile(exportOption.DataImportPath + exportExcelFileName)
+	diags, err := ExportAllWithDiagnostics(exportOption, exportExcelFileName, exportSheetName)
+	diags.Print()
 	if err != nil {
-		color.Red("open excel err:%v file:%v", err, exportOption.DataImportPath+exportExcelFileName)
 		return err
 	}
+	fmt.Println(fmt.Sprintf("Diagnostics errors:%v warnings:%v", diags.Count(SeverityError), diags.Count(SeverityWarning)))
+	return nil
+}
+
+// 配置了FailOnError或FailOnDuplicateKey时,有对应的诊断信息就中止导出
+func checkExportFailure(exportOption *ExportOption, diags *Diagnostics) error {
+	if exportOption.FailOnError && diags.HasError() {
+		return ErrExportHasError
+	}
+	if exportOption.FailOnDuplicateKey {
+		duplicateKeyDiags := diags.Filter(func(diag *Diagnostic) bool {
+			return diag.Code == DiagnosticCodeDuplicateKey
+		})
+		if len(duplicateKeyDiags.Items()) > 0 {
+			return ErrExportHasDupKeys
+		}
+	}
+	return nil
+}
+
+// 从一个总表导出所有的配置表
+// 返回导出过程中收集的诊断信息
+func ExportAllWithDiagnostics(exportOption *ExportOption, exportExcelFileName, exportSheetName string) (*Diagnostics, error) {
+	checkExportOption(exportOption)
+	diags := NewDiagnostics()
+	if err := checkCompressOption(exportOption); err != nil {
+		color.Red("%v", err)
+		return diags, err
+	}
+	encryptKey, err := exportOption.GetEncryptKey()
+	if err != nil {
+		color.Red("%v", err)
+		return diags, err
+	}
 	sheets, err := parseExportSheets(exportOption.DataImportPath+exportExcelFileName, exportSheetName)
-	f.Close()
 	if err != nil {
 		color.Red("ConvertSheetErr err:%v sheet:%v", err, exportSheetName)
-		return err
+		return diags, err
 	}
 	fmt.Println(fmt.Sprintf("parseExportSheets excel:%v sheet:%v count:%v", exportExcelFileName, exportSheetName, len(sheets)))
 	getMapValueFn := func(strMap map[string]any, key, defaultValue string) string {
@@ -76,14 +146,12 @@ func ExportAll(exportOption *ExportOption, exportExcelFileName, exportSheetName
 		}
 		return defaultValue
 	}
-	generateInfo := &GenerateInfo{}
+	generateInfo := &GenerateInfo{skipUnchanged: exportOption.watching}
 	for idx, templateFile := range exportOption.CodeTemplateFiles {
 		generateInfo.TemplateFiles = append(generateInfo.TemplateFiles, exportOption.CodeTemplatePath+templateFile)
 		generateInfo.ExportFiles = append(generateInfo.ExportFiles, exportOption.CodeExportFiles[idx])
 	}
-	exportInfoMap := make(map[string]*ExportInfo)
-	orderNames := make([]string, 0)
-	refCheckMap := make(map[string]*ExportInfo)
+	tasks := make([]*convertTask, 0, len(sheets))
 	for _, v := range sheets {
 		exportCfg := v.(map[string]any)
 		excelName := getMapValueFn(exportCfg, "Excel", "")
@@ -101,42 +169,102 @@ func ExportAll(exportOption *ExportOption, exportExcelFileName, exportSheetName
 			MessageName: getMapValueFn(exportCfg, "Message", sheetName),
 			MgrType:     getMapValueFn(exportCfg, "MgrType", "map"),
 		}
-		if sheetOption.MgrType == "map" {
+		if isKeyedMgrType(sheetOption.MgrType) {
 			sheetOption.MapKeyName = getMapValueFn(exportCfg, "MapKey", "")
 		}
-		codeComment := getMapValueFn(exportCfg, "CodeComment", "")
-		mergeName := getMapValueFn(exportCfg, "Merge", "")
-		excelFileName := getMapValueFn(exportCfg, "Excel", "")
+		sheetOption.Layout = getMapValueFn(exportCfg, "Layout", "")
 		//exportFileName := getMapValueFn(exportCfg, "ExportName", sheetName)
-		f, err = excelize.OpenFile(exportOption.DataImportPath + excelFileName)
+		tasks = append(tasks, &convertTask{
+			ExcelFileName: getMapValueFn(exportCfg, "Excel", ""),
+			SheetOption:   sheetOption,
+			MsgDesc:       FindMessageDescriptor(sheetOption.MessageName),
+			MergeName:     getMapValueFn(exportCfg, "Merge", ""),
+			CodeComment:   getMapValueFn(exportCfg, "CodeComment", ""),
+		})
+	}
+	// 增量导出,excel文件和schema都没有变化的sheet直接使用缓存的转换结果
+	schemaHash := ""
+	if exportOption.useExportCache() {
+		schemaHash, err = computeSchemaHash(exportOption)
 		if err != nil {
-			color.Red("open excel err:%v file:%v", err, exportOption.DataImportPath+excelFileName)
-			return err
+			color.Red("computeSchemaHash err:%v", err)
+			return diags, err
+		}
+		cache := loadExportCache(exportOption, schemaHash)
+		fileHashes := make(map[string]string)
+		for _, task := range tasks {
+			// 读取失败的文件交给转换任务报错
+			if task.ExcelHash, err = getFileHash(fileHashes, exportOption.DataImportPath+task.ExcelFileName); err == nil {
+				cache.apply(task, task.ExcelHash)
+			}
 		}
-		sheetData, err := ConvertSheet(exportOption, f, sheetOption)
-		f.Close()
-		if err != nil {
-			color.Red("ConvertSheetErr err:%v sheet:%v", err, sheetOption.SheetName)
-			return err
+	}
+	// 并发转换,然后按总表的顺序处理转换结果,保证合并和生成代码的顺序是确定的
+	runConvertTasks(exportOption, tasks)
+	if exportOption.useExportCache() && !hasTaskError(tasks) {
+		if err = saveExportCache(exportOption, schemaHash, tasks); err != nil {
+			color.Yellow("save cache err:%v file:%v", err, exportOption.CacheFile)
+		}
+	}
+	exportInfoMap := make(map[string]*ExportInfo)
+	orderNames := make([]string, 0)
+	refCheckMap := make(map[string]*ExportInfo)
+	mergeKeySources := make(map[string]map[string]string)               // mergeName -> key -> key所在的excel,sheet和单元格
+	mergeUniqueSources := make(map[string]map[string]map[string]string) // mergeName -> 列名 -> #Unique的值 -> 值所在的excel,sheet和单元格
+	for _, task := range tasks {
+		diags.Append(task.Diags)
+		excelName := task.SheetOption.ExcelName
+		sheetName := task.SheetOption.SheetName
+		excelFileName := task.ExcelFileName
+		mergeName := task.MergeName
+		if task.Err != nil {
+			color.Red("ConvertSheetErr err:%v excel:%v sheet:%v", task.Err, excelFileName, sheetName)
+			return diags, task.Err
+		}
+		sheetData := task.Data
+		sheetOption := task.ResultOption
+		if task.Cached {
+			fmt.Println(fmt.Sprintf("use cache excel:%v sheet:%v", excelFileName, sheetOption.SheetName))
+		} else {
+			fmt.Println(fmt.Sprintf("parse excel:%v sheet:%v", excelFileName, sheetOption.SheetName))
 		}
-		fmt.Println(fmt.Sprintf("parse excel:%v sheet:%v", excelFileName, sheetOption.SheetName))
 		if mergeName == "" {
 			exportInfoMap[excelName+"."+sheetName] = &ExportInfo{
 				MgrData:     sheetData,
 				SheetOption: sheetOption,
-				CodeComment: codeComment,
+				CodeComment: task.CodeComment,
 				//ExportFileName: exportFileName,
 			}
+			exportInfoMap[excelName+"."+sheetName].addRowSources(sheetOption, 0)
 			orderNames = append(orderNames, excelName+"."+sheetName)
 			refCheckMap[sheetName] = exportInfoMap[excelName+"."+sheetName]
 		} else {
 			if mergeInfo, ok := exportInfoMap[mergeName]; ok {
-				mergeData, err := mergeMgrData(mergeInfo.MgrData, sheetData)
+				rowOffset := 0 // slice合并后的下标偏移
+				if rows, ok := mergeInfo.MgrData.([]any); ok {
+					rowOffset = len(rows)
+				}
+				mergeData, duplicateKeys, err := mergeMgrData(mergeInfo.MgrData, sheetData)
 				if err != nil {
 					color.Red("mergeMgrDataErr excel:%v sheet:%v merge:%v err:%v",
 						excelFileName, sheetName, mergeName, err)
-					return err
+					return diags, err
 				}
+				keySources := mergeKeySources[mergeName]
+				for _, key := range duplicateKeys {
+					keyStr, _ := refValueString(key)
+					diags.Add(&Diagnostic{
+						Severity:  getDuplicateKeySeverity(exportOption),
+						Code:      DiagnosticCodeDuplicateKey,
+						ExcelName: excelName,
+						SheetName: sheetName,
+						Cell:      sheetOption.KeyCells[keyStr],
+						Message:   fmt.Sprintf("%v %v in merge:%v, previous at %v", duplicateKeyMessage, key, mergeName, keySources[keyStr]),
+					})
+				}
+				addMergeKeySources(keySources, sheetOption)
+				checkMergeUniqueValues(mergeUniqueSources[mergeName], sheetOption, mergeName, diags)
+				mergeInfo.addRowSources(sheetOption, rowOffset)
 				mergeInfo.MgrData = mergeData
 				fmt.Println(fmt.Sprintf("merge:%v excel:%v sheet:%v", mergeName, excelFileName, sheetOption.SheetName))
 			} else {
@@ -144,26 +272,58 @@ func ExportAll(exportOption *ExportOption, exportExcelFileName, exportSheetName
 					MgrData:     sheetData,
 					SheetOption: sheetOption,
 					MergeName:   mergeName,
-					CodeComment: codeComment,
+					CodeComment: task.CodeComment,
 				}
+				exportInfoMap[mergeName].addRowSources(sheetOption, 0)
 				orderNames = append(orderNames, mergeName)
 				refCheckMap[mergeName] = exportInfoMap[mergeName]
+				mergeKeySources[mergeName] = make(map[string]string)
+				addMergeKeySources(mergeKeySources[mergeName], sheetOption)
+				mergeUniqueSources[mergeName] = make(map[string]map[string]string)
+				checkMergeUniqueValues(mergeUniqueSources[mergeName], sheetOption, mergeName, diags)
 			}
 		}
 	}
 
+	// ref功能,检查数据关联
+	checkRefs(exportInfoMap, orderNames, refCheckMap, diags)
+	if err := checkExportFailure(exportOption, diags); err != nil {
+		return diags, err
+	}
+
 	enabledFormats := getEnabledExportFormats(exportOption.ExportFormats)
 	// 导出
 	md5Map := make(map[int]map[string]string)
 	for i := range exportOption.ExportFormats {
 		md5Map[i] = make(map[string]string)
 	}
-	for _, exportInfo := range exportInfoMap {
+	// 写入导出文件,并记录md5
+	// 配置了压缩时,写入压缩后的数据,文件名加上压缩扩展名,md5也是压缩后的数据的md5
+	writeExportFileFn := func(format, exportFileName, mergeName string, data []byte) error {
+		idx := enabledFormats[format]
+		exportFileName, data, err := compressData(exportOption.GetCompress(format), exportFileName, data)
+		if err != nil {
+			color.Red("compressDataErr exportFileName:%v merge:%v err:%v", exportFileName, mergeName, err)
+			return err
+		}
+		exportPath := exportOption.DataExportPath[idx]
+		if err = writeOutputFile(filepath.Join(exportPath, exportFileName), data, exportOption.watching); err != nil {
+			color.Red("ExportAllErr exportFileName:%v merge:%v err:%v", exportFileName, mergeName, err)
+			return err
+		}
+		if idx < len(exportOption.Md5ExportPath) {
+			md5Map[idx][exportFileName] = GetMd5(data)
+		}
+		return nil
+	}
+	var bundleTables []*bundleTable
+	for _, name := range orderNames {
+		exportInfo := exportInfoMap[name]
 		jsonData, err := json.MarshalIndent(exportInfo.MgrData, "", "  ")
 		if err != nil {
 			color.Red("ExportAllErr exportFileName:%v merge:%v err:%v",
 				exportInfo.SheetOption.ExportFileName, exportInfo.MergeName, err)
-			return err
+			return diags, err
 		}
 		exportFileNameWithoutExt := ""
 		if exportInfo.MergeName == "" {
@@ -171,38 +331,86 @@ func ExportAll(exportOption *ExportOption, exportExcelFileName, exportSheetName
 		} else {
 			exportFileNameWithoutExt = exportInfo.MergeName
 		}
-		if idx, ok := enabledFormats["json"]; ok {
+		if _, ok := enabledFormats["json"]; ok {
 			exportFileName := fmt.Sprintf("%s.json", exportFileNameWithoutExt)
-			exportPath := exportOption.DataExportPath[idx]
-			err = os.WriteFile(filepath.Join(exportPath, exportFileName), jsonData, os.ModePerm)
-			if err != nil {
-				color.Red("ExportAllErr exportFileName:%v merge:%v err:%v",
-					exportFileName, exportInfo.MergeName, err)
-				return err
-			}
-			if idx < len(exportOption.Md5ExportPath) {
-				md5Map[idx][exportFileName] = GetMd5(jsonData)
+			if err = writeExportFileFn("json", exportFileName, exportInfo.MergeName, jsonData); err != nil {
+				return diags, err
 			}
 		}
-		if idx, ok := enabledFormats["pb"]; ok {
+		_, pbEnabled := enabledFormats["pb"]
+		_, bundleEnabled := enabledFormats["bundle"]
+		if pbEnabled || bundleEnabled {
 			pbData, pbErr := marshalToProtoBinary(exportInfo.MgrData, exportInfo.SheetOption)
 			if pbErr != nil {
 				color.Red("marshalToProtoBinaryErr exportFileName:%v merge:%v err:%v",
 					exportFileNameWithoutExt, exportInfo.MergeName, pbErr)
-				return pbErr
+				return diags, pbErr
+			}
+			if encryptKey != nil {
+				if pbData, pbErr = encryptData(encryptKey, pbData); pbErr != nil {
+					color.Red("encryptDataErr exportFileName:%v merge:%v err:%v",
+						exportFileNameWithoutExt, exportInfo.MergeName, pbErr)
+					return diags, pbErr
+				}
 			}
 			exportFileName := fmt.Sprintf("%s.pb", exportFileNameWithoutExt)
-			exportPath := exportOption.DataExportPath[idx]
-			err = os.WriteFile(filepath.Join(exportPath, exportFileName), pbData, os.ModePerm)
-			if err != nil {
-				color.Red("ExportAllErr exportFileName:%v merge:%v err:%v",
-					exportFileName, exportInfo.MergeName, err)
-				return err
+			if _, ok := enabledFormats["pb"]; ok {
+				if err = writeExportFileFn("pb", exportFileName, exportInfo.MergeName, pbData); err != nil {
+					return diags, err
+				}
+			}
+			// 打包文件里的配置表使用pb格式
+			bundleTables = append(bundleTables, &bundleTable{
+				Name: exportFileName,
+				Data: pbData,
+			})
+		}
+		if _, ok := enabledFormats["lua"]; ok {
+			luaData, luaErr := marshalToLua(exportInfo.MgrData, exportInfo.SheetOption)
+			if luaErr != nil {
+				color.Red("marshalToLuaErr exportFileName:%v merge:%v err:%v",
+					exportFileNameWithoutExt, exportInfo.MergeName, luaErr)
+				return diags, luaErr
+			}
+			exportFileName := fmt.Sprintf("%s.lua", exportFileNameWithoutExt)
+			if err = writeExportFileFn("lua", exportFileName, exportInfo.MergeName, luaData); err != nil {
+				return diags, err
+			}
+		}
+		if _, ok := enabledFormats["yaml"]; ok {
+			yamlData, yamlErr := marshalToYaml(exportInfo.MgrData)
+			if yamlErr != nil {
+				color.Red("marshalToYamlErr exportFileName:%v merge:%v err:%v",
+					exportFileNameWithoutExt, exportInfo.MergeName, yamlErr)
+				return diags, yamlErr
 			}
-			if idx < len(exportOption.Md5ExportPath) {
-				md5Map[idx][exportFileName] = GetMd5(pbData)
+			exportFileName := fmt.Sprintf("%s.yaml", exportFileNameWithoutExt)
+			if err = writeExportFileFn("yaml", exportFileName, exportInfo.MergeName, yamlData); err != nil {
+				return diags, err
 			}
 		}
+		if _, ok := enabledFormats["prototext"]; ok {
+			textData, textErr := marshalToPrototext(exportInfo.MgrData, exportInfo.SheetOption)
+			if textErr != nil {
+				color.Red("marshalToPrototextErr exportFileName:%v merge:%v err:%v",
+					exportFileNameWithoutExt, exportInfo.MergeName, textErr)
+				return diags, textErr
+			}
+			exportFileName := fmt.Sprintf("%s.txtpb", exportFileNameWithoutExt)
+			if err = writeExportFileFn("prototext", exportFileName, exportInfo.MergeName, textData); err != nil {
+				return diags, err
+			}
+		}
+	}
+	if _, ok := enabledFormats["bundle"]; ok {
+		bundleData, bundleErr := marshalBundle(bundleTables)
+		if bundleErr != nil {
+			color.Red("marshalBundleErr err:%v", bundleErr)
+			return diags, bundleErr
+		}
+		if err = writeExportFileFn("bundle", exportOption.GetBundleFile(), "", bundleData); err != nil {
+			return diags, err
+		}
 	}
 	for formatIdx, md5FilePath := range exportOption.Md5ExportPath {
 		if md5FilePath == "" {
@@ -211,16 +419,17 @@ func ExportAll(exportOption *ExportOption, exportExcelFileName, exportSheetName
 		md5Data, err := json.MarshalIndent(md5Map[formatIdx], "", "  ")
 		if err != nil {
 			color.Red("export md5 err:%v", err)
-			return err
+			return diags, err
 		}
-		err = os.WriteFile(md5FilePath, md5Data, os.ModePerm)
+		err = writeOutputFile(md5FilePath, md5Data, exportOption.watching)
 		if err != nil {
 			color.Red("export md5 err:%v", err)
-			return err
+			return diags, err
 		}
 	}
 
 	// 生成代码
+	usedKeyTypeNames := make(map[string]struct{})
 	for _, name := range orderNames {
 		exportInfo := exportInfoMap[name]
 		exportFileName := ""
@@ -241,43 +450,21 @@ func ExportAll(exportOption *ExportOption, exportExcelFileName, exportSheetName
 			FileName:    exportFileName,
 			CodeComment: exportInfo.CodeComment,
 		}
-		if mgrInfo.MgrType == "map" {
+		if isKeyedMgrType(mgrInfo.MgrType) {
 			mgrInfo.MapKeyType = exportInfo.SheetOption.MapKeyType
 			if mgrInfo.MapKeyType == "int32" || mgrInfo.MapKeyType == "int64" || mgrInfo.MapKeyType == "uint64" {
 				mgrInfo.MapKeyType = "int"
 			}
+			setMapKeyInfo(mgrInfo, FindMessageDescriptor(mgrInfo.MessageName), exportInfo.SheetOption.MapKeyName, usedKeyTypeNames)
 		}
 		generateInfo.AddDataMgrInfo(mgrInfo)
 	}
 	err = GenerateCode(generateInfo)
 	if err != nil {
 		color.Red("GenerateCodeErr err:%v", err)
-		return err
-	}
-	// ref功能,检查数据关联
-	for _, exportInfo := range exportInfoMap {
-		for _, columnOption := range exportInfo.SheetOption.ColumnOpts {
-			if columnOption.Ref == "" {
-				continue
-			}
-			sheetName := exportInfo.SheetOption.SheetName
-			sheetData := exportInfo.MgrData
-			refInfo, ok := refCheckMap[columnOption.Ref]
-			if !ok {
-				color.Red("ref not exists sheetName:%v column:%v ref:%v", sheetName, columnOption.Name, columnOption.Ref)
-				continue
-			}
-			refKeyName := refInfo.SheetOption.MapKeyName
-			rangeSheetData(sheetData, columnOption.Name, refKeyName, func(checkId int32) {
-				if refSheetDataMap, ok := refInfo.MgrData.(map[int32]any); ok {
-					if _, ok := refSheetDataMap[checkId]; !ok {
-						color.Red("ref ERROR sheetName:%v column:%v ref:%v checkId:%v", sheetName, columnOption.Name, columnOption.Ref, checkId)
-					}
-				}
-			})
-		}
+		return diags, err
 	}
-	return nil
+	return diags, nil
 }
 
 func getEnabledExportFormats(formats []string) map[string]int {
@@ -290,7 +477,8 @@ func getEnabledExportFormats(formats []string) map[string]int {
 	result = make(map[string]int)
 	for i, format := range formats {
 		f := strings.ToLower(strings.TrimSpace(format))
-		if f == "json" || f == "pb" {
+		switch f {
+		case "json", "pb", "lua", "yaml", "prototext", "bundle":
 			result[f] = i
 		}
 	}
@@ -307,27 +495,35 @@ func marshalToProtoBinary(v any, sheetOption *SheetOption) ([]byte, error) {
 	}
 	msgType := msgDesc.UnwrapMessage()
 	switch sheetOption.MgrType {
-	case "map":
+	case "map", "group":
 		buffer := bytes.NewBuffer(nil)
-		dataMap, ok := v.(map[int32]any)
-		if !ok {
+		// key可以是任意整数类型或者字符串(如组合key),按key排序保证输出稳定
+		dataMap := reflect.ValueOf(v)
+		if dataMap.Kind() != reflect.Map {
 			return nil, fmt.Errorf("invalid map data type: %T", v)
 		}
-		keys := make([]int32, 0, len(dataMap))
-		for k := range dataMap {
-			keys = append(keys, k)
-		}
-		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		keys := dataMap.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return lessMapKey(keys[i], keys[j]) })
+		delimOpts := protodelim.MarshalOptions{}
+		delimOpts.Deterministic = true
 		for _, k := range keys {
-			row := dataMap[k]
-			msg, err := toDynamicProtoMessage(msgType, row)
-			if err != nil {
-				return nil, err
+			rows := []any{dataMap.MapIndex(k).Interface()}
+			// group类型的每一行依次写入,加载时根据行数据重新分组
+			if sheetOption.MgrType == "group" {
+				groupRows, ok := rows[0].([]any)
+				if !ok {
+					return nil, fmt.Errorf("invalid group data type: %T", rows[0])
+				}
+				rows = groupRows
 			}
-			delimOpts := protodelim.MarshalOptions{}
-			delimOpts.Deterministic = true
-			if _, err = delimOpts.MarshalTo(buffer, msg); err != nil {
-				return nil, err
+			for _, row := range rows {
+				msg, err := toDynamicProtoMessage(msgType, row)
+				if err != nil {
+					return nil, err
+				}
+				if _, err = delimOpts.MarshalTo(buffer, msg); err != nil {
+					return nil, err
+				}
 			}
 		}
 		return buffer.Bytes(), nil
@@ -372,57 +568,84 @@ func toDynamicProtoMessage(msgType protoreflect.MessageDescriptor, v any) (proto
 	return msg, nil
 }
 
-func mergeMgrData(dst, src any) (any, error) {
-	switch m := dst.(type) {
-	case map[int32]any:
-		if m2, ok := src.(map[int32]any); ok {
-			for k, v := range m2 {
-				m[k] = v
+// 合并多个sheet的数据,返回合并后的数据和重复的key,重复的key使用后面的sheet的数据
+func mergeMgrData(dst, src any) (any, []any, error) {
+	if m, ok := dst.([]any); ok {
+		m2, ok := src.([]any)
+		if !ok {
+			return dst, nil, fmt.Errorf("merge type mismatch: %T %T", dst, src)
+		}
+		return append(m, m2...), nil, nil
+	}
+	dstValue := reflect.ValueOf(dst)
+	if dstValue.Kind() != reflect.Map {
+		return dst, nil, fmt.Errorf("unsupported type: %T", dst)
+	}
+	srcValue := reflect.ValueOf(src)
+	if srcValue.Type() != dstValue.Type() {
+		return dst, nil, fmt.Errorf("merge type mismatch: %T %T", dst, src)
+	}
+	keys := srcValue.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return lessMapKey(keys[i], keys[j])
+	})
+	var duplicateKeys []any
+	for _, key := range keys {
+		if dstElem := dstValue.MapIndex(key); dstElem.IsValid() {
+			// group类型相同key的行合并到一起
+			dstRows, isDstGroup := dstElem.Interface().([]any)
+			srcRows, isSrcGroup := srcValue.MapIndex(key).Interface().([]any)
+			if isDstGroup && isSrcGroup {
+				dstValue.SetMapIndex(key, reflect.ValueOf(append(dstRows, srcRows...)))
+				continue
 			}
+			duplicateKeys = append(duplicateKeys, key.Interface())
 		}
-		return m, nil
-	case []any:
-		if m2, ok := src.([]any); ok {
-			m = append(m, m2...)
-		}
-		return m, nil
-	default:
-		return dst, fmt.Errorf("unsupported type: %T", dst)
+		dstValue.SetMapIndex(key, srcValue.MapIndex(key))
 	}
+	return dst, duplicateKeys, nil
 }
 
-func rangeSheetData(sheetData any, columnName, refKeyName string, fn func(checkId int32)) {
-	switch t := sheetData.(type) {
-	case map[int32]any:
-		for _, row := range t {
-			if m, ok := row.(map[string]any); ok {
-				columnValue := m[columnName]
-				switch ct := columnValue.(type) {
-				case []any: // repeated
-					for _, elem := range ct {
-						rangeElem(elem, refKeyName, fn)
-					}
-				default:
-					rangeElem(columnValue, refKeyName, fn)
-				}
-			}
-		}
+// 记录合并的sheet中每个key的来源
+func addMergeKeySources(keySources map[string]string, sheetOption *SheetOption) {
+	for key, cell := range sheetOption.KeyCells {
+		keySources[key] = fmt.Sprintf("%v %v %v", sheetOption.ExcelName, sheetOption.SheetName, cell)
 	}
 }
 
-func rangeElem(columnValue any, refKeyName string, fn func(checkId int32)) {
-	switch ct := columnValue.(type) {
-	case int32:
-		fn(columnValue.(int32)) // NOTE:暂时只处理int32
-	case map[string]any:
-		// NOTE:暂时只处理int32的map key
-		if cfgId, ok := ct[refKeyName].(int32); ok {
-			fn(cfgId)
+// 合并的sheet之间也要检查#Unique,uniqueSources记录已经合并的sheet中的值
+func checkMergeUniqueValues(uniqueSources map[string]map[string]string, sheetOption *SheetOption, mergeName string, diags *Diagnostics) {
+	columnNames := make([]string, 0, len(sheetOption.UniqueCells))
+	for columnName := range sheetOption.UniqueCells {
+		columnNames = append(columnNames, columnName)
+	}
+	sort.Strings(columnNames)
+	for _, columnName := range columnNames {
+		cells := sheetOption.UniqueCells[columnName]
+		values := make([]string, 0, len(cells))
+		for value := range cells {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		sources, ok := uniqueSources[columnName]
+		if !ok {
+			sources = make(map[string]string)
+			uniqueSources[columnName] = sources
+		}
+		for _, value := range values {
+			if firstSource, ok := sources[value]; ok {
+				diags.Add(&Diagnostic{
+					Severity:  SeverityError,
+					ExcelName: sheetOption.ExcelName,
+					SheetName: sheetOption.SheetName,
+					Cell:      cells[value],
+					Column:    columnName,
+					Message:   fmt.Sprintf("duplicate value %v in merge:%v, first at %v", value, mergeName, firstSource),
+				})
+				continue
+			}
+			sources[value] = fmt.Sprintf("%v %v %v", sheetOption.ExcelName, sheetOption.SheetName, cells[value])
 		}
-		//case []any: // repeated
-		//	for _, elem := range ct {
-		//		rangeElem(elem, refKeyName, fn)
-		//	}
 	}
 }
 
@@ -469,30 +692,51 @@ func GetMd5(bytes []byte) string {
 }
 
 func ExportByConfig(configFile string) error {
+	options, err := LoadExportOption(configFile)
+	if err != nil {
+		return err
+	}
+	return ExportByOption(options)
+}
+
+// 读取导出配置文件
+func LoadExportOption(configFile string) (*ExportOption, error) {
 	fileData, err := os.ReadFile(configFile)
 	if err != nil {
 		color.Red("read config err:%v file:%v", err, configFile)
-		return err
+		return nil, err
 	}
 	options := &ExportOption{}
 	err = yaml.Unmarshal(fileData, options)
 	if err != nil {
 		color.Red("parse yaml config err:%v file:%v", err, configFile)
-		return err
+		return nil, err
 	}
 	if len(options.CodeTemplateFiles) != len(options.CodeExportFiles) {
 		color.Red("len(CodeTemplateFiles) != len(CodeExportFiles) file:%v", configFile)
-		return errors.New("len(CodeTemplateFiles) != len(CodeExportFiles)")
+		return nil, errors.New("len(CodeTemplateFiles) != len(CodeExportFiles)")
 	}
+	return options, nil
+}
+
+// 解析proto文件,并导出总表中的所有配置表
+func ExportByOption(options *ExportOption) error {
 	if len(options.ProtoFiles) > 0 {
-		err = ParseProtoFile([]string{options.ProtoPath}, options.ProtoFiles...)
+		err := ParseProtoFile([]string{options.ProtoPath}, options.ProtoFiles...)
 		if err != nil {
 			color.Red("ParseProtoFile err:%v", err)
 			return err
 		}
 	}
-	err = ExportAll(options, options.ExportAllExcelFile, options.ExportAllSheet)
-	return err
+	return ExportAll(options, options.ExportAllExcelFile, options.ExportAllSheet)
+}
+
+// 打包文件名,导出在bundle格式对应的DataExportPath
+func (opt *ExportOption) GetBundleFile() string {
+	if opt.BundleFile == "" {
+		return "cfg.bundle"
+	}
+	return opt.BundleFile
 }
 
 func checkExportOption(opt *ExportOption) {
@@ -514,7 +758,7 @@ func autoCheckDir(dir *string) {
 }
 
 func parseExportSheets(excel, exportSheetName string) ([]any, error) {
-	f, err := excelize.OpenFile(excel)
+	f, err := OpenSourceFile(excel)
 	if err != nil {
 		color.Red("open excel err:%v file:%v", err, excel)
 		return nil, err
@@ -530,12 +774,12 @@ func parseExportSheets(excel, exportSheetName string) ([]any, error) {
 }
 
 // 解析导出总表
-func parseExportSheetsFromFile(excelFile *excelize.File, exportSheetName string) ([]any, error) {
+func parseExportSheetsFromFile(source SourceFile, exportSheetName string) ([]any, error) {
 	opt := &SheetOption{
 		SheetName: exportSheetName,
 		MgrType:   "slice",
 	}
-	rows, err := excelFile.Rows(opt.SheetName)
+	rows, err := source.Rows(opt.SheetName)
 	if err != nil {
 		color.Red("sheet:%v err:%v", opt.SheetName, err)
 		return nil, err
@@ -1,6 +1,7 @@
 package tool
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/fatih/color"
 	"os"
@@ -11,10 +12,29 @@ import (
 type DataMgrInfo struct {
 	MessageName string // proto message name
 	MgrName     string
-	MgrType     string // map slice object
-	MapKeyType  string // int int32 int64 uint uint32 uint64 string(MgrType=map时才有效)
+	MgrType     string // map slice object group
+	MapKeyType  string // int int32 int64 uint uint32 uint64 string(MgrType=map group时才有效,组合key为string)
 	FileName    string // 导出文件名,不含目录
 	CodeComment string // 代码注释
+
+	// 以下字段MgrType=map group时才有效,用于生成非int32 key的管理类和组合key的访问接口
+	MapKeyFields    []*MapKeyField // key字段,组合key有多个
+	MapKeyGoType    string         // go代码中的key类型,如string uint32,组合key为生成的结构体名
+	MapKeyGetFnName string         // 组合key的访问接口名,如GetActivityStageCfg
+}
+
+// map key的字段
+type MapKeyField struct {
+	Name    string // json name
+	GoName  string // go结构体字段名
+	GoType  string // int32 int64 uint32 uint64 string
+	ArgName string // 访问接口的参数名
+	Getter  string // 从配置项获取字段值的代码,如GetActivityId(),展开的子字段如GetChild().GetFieldName()
+}
+
+// 是否是组合key
+func (info *DataMgrInfo) IsCompositeKey() bool {
+	return len(info.MapKeyFields) > 1
 }
 
 type GenerateInfo struct {
@@ -22,6 +42,8 @@ type GenerateInfo struct {
 	TemplateFiles []string
 	ExportFiles   []string
 	Mgrs          []*DataMgrInfo
+
+	skipUnchanged bool // watch模式,内容没有变化的代码文件不再写入
 }
 
 func (g *GenerateInfo) AddDataMgrInfo(info *DataMgrInfo) {
@@ -41,15 +63,14 @@ func GenerateCode(generateInfo *GenerateInfo) error {
 			color.Red("create %v err:%v", path.Dir(codeFileName), err)
 			return err
 		}
-		outFile, err := os.OpenFile(codeFileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+		buffer := bytes.NewBuffer(nil)
+		err = tmpl.Execute(buffer, generateInfo)
 		if err != nil {
-			color.Red("open %v err:%v", codeFileName, err)
+			color.Red("tmpl.Execute %v err:%v", codeFileName, err)
 			return err
 		}
-		defer outFile.Close()
-		err = tmpl.Execute(outFile, generateInfo)
-		if err != nil {
-			color.Red("tmpl.Execute %v err:%v", codeFileName, err)
+		if err = writeOutputFile(codeFileName, buffer.Bytes(), generateInfo.skipUnchanged); err != nil {
+			color.Red("write %v err:%v", codeFileName, err)
 			return err
 		}
 		fmt.Println(fmt.Sprintf("GenerateCode export:%v template:%v", codeFileName, templateFile))
@@ -0,0 +1,120 @@
+package tool
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestConvertSheetGroup(t *testing.T) {
+	initProtoForTest(t)
+	f := newTestSheetFile(t, "ItemNum",
+		[]interface{}{"CfgId", "Num"},
+		[]interface{}{"1", "10"},
+		[]interface{}{"2", "5"},
+		[]interface{}{"1", "20"},
+	)
+	defer func() { _ = f.Close() }()
+	diags := NewDiagnostics()
+	data, resultOpt, err := convertSheet(&ExportOption{}, NewExcelSource(f),
+		&SheetOption{ExcelName: "item.xlsx", SheetName: "ItemNum", MessageName: "ItemNum", MgrType: "group"},
+		FindMessageDescriptor("ItemNum"), diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 相同key的行不算重复
+	if len(diags.Items()) != 0 {
+		t.Fatalf("unexpected diags: %v", diags.Items())
+	}
+	if resultOpt.MapKeyName != "CfgId" || resultOpt.MapKeyType != "int32" {
+		t.Fatalf("unexpected key: %v %v", resultOpt.MapKeyName, resultOpt.MapKeyType)
+	}
+	want := map[int32]any{
+		1: []any{map[string]any{"CfgId": int32(1), "Num": int32(10)}, map[string]any{"CfgId": int32(1), "Num": int32(20)}},
+		2: []any{map[string]any{"CfgId": int32(2), "Num": int32(5)}},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Fatalf("got:%v\nwant:%v", data, want)
+	}
+
+	// 合并的sheet中相同key的行追加到一起
+	merged, duplicateKeys, err := mergeMgrData(data, map[int32]any{
+		1: []any{map[string]any{"CfgId": int32(1), "Num": int32(30)}},
+	})
+	if err != nil || len(duplicateKeys) != 0 || len(merged.(map[int32]any)[1].([]any)) != 3 {
+		t.Fatalf("unexpected merge: %v %v %v", merged, duplicateKeys, err)
+	}
+
+	luaData, err := marshalToLua(merged, resultOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantLua := `return {
+  [1] = {
+    {
+      CfgId = 1,
+      Num = 10,
+    },
+    {
+      CfgId = 1,
+      Num = 20,
+    },
+    {
+      CfgId = 1,
+      Num = 30,
+    },
+  },
+  [2] = {
+    {
+      CfgId = 2,
+      Num = 5,
+    },
+  },
+}
+`
+	if string(luaData) != wantLua {
+		t.Fatalf("got lua:\n%v", string(luaData))
+	}
+	textData, err := marshalToPrototext(merged, resultOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantText := `# 1 [0]
+CfgId: 1
+Num: 10
+
+# 1 [1]
+CfgId: 1
+Num: 20
+
+# 1 [2]
+CfgId: 1
+Num: 30
+
+# 2 [0]
+CfgId: 2
+Num: 5
+`
+	if !bytes.Equal(textData, []byte(wantText)) {
+		t.Fatalf("got prototext:\n%v", string(textData))
+	}
+	pbData, err := marshalToProtoBinary(merged, resultOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每行一个message:长度前缀(1字节)+CfgId(2字节)+Num(2字节)
+	if len(pbData) != 4*5 {
+		t.Fatalf("unexpected pb data len: %v", len(pbData))
+	}
+
+	// 可以关联group表的key,也可以关联group表中每一行的字段
+	refCheckMap := map[string]*ExportInfo{"ItemNum": {MgrData: merged, SheetOption: resultOpt}}
+	target, err := newRefTarget("ItemNum", refCheckMap)
+	if err != nil || len(target.values) != 2 {
+		t.Fatalf("unexpected ref target: %v %v", target, err)
+	}
+	target, err = newRefTarget("ItemNum.Num", refCheckMap)
+	if err != nil || len(target.values) != 4 {
+		t.Fatalf("unexpected ref target: %v %v", target, err)
+	}
+}
@@ -0,0 +1,19 @@
+package tool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 把测试用的proto写到临时目录后解析,可以import ../proto中的proto文件和well-known types
+func initTempProtoForTest(t *testing.T, fileName, src string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, fileName), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ParseProtoFile([]string{dir, "./../proto"}, fileName); err != nil {
+		t.Fatal(err)
+	}
+}
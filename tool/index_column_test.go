@@ -0,0 +1,172 @@
+package tool
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseColumnPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		want     []columnPathSegment
+		wantOk   bool
+		stripped string
+	}{
+		{"Rewards[0].CfgId", []columnPathSegment{{"Rewards", 0}, {"CfgId", -1}}, true, "Rewards.CfgId"},
+		{"NextQuests[12]", []columnPathSegment{{"NextQuests", 12}}, true, "NextQuests"},
+		{"Base.Pos.X", []columnPathSegment{{"Base", -1}, {"Pos", -1}, {"X", -1}}, true, "Base.Pos.X"},
+		{"Rewards[-1]", nil, false, "Rewards[-1]"},
+		{"Rewards[a]", nil, false, "Rewards[a]"},
+		{"Rewards[0", nil, false, "Rewards[0"},
+		{"[0].CfgId", nil, false, "[0].CfgId"},
+		{"Rewards[0][1]", nil, false, "Rewards[0][1]"},
+	}
+	for _, tt := range tests {
+		got, ok := parseColumnPath(tt.name)
+		if ok != tt.wantOk || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseColumnPath(%v)=%v,%v want:%v,%v", tt.name, got, ok, tt.want, tt.wantOk)
+		}
+		if stripped := stripColumnIndex(tt.name); stripped != tt.stripped {
+			t.Errorf("stripColumnIndex(%v)=%v want:%v", tt.name, stripped, tt.stripped)
+		}
+	}
+}
+
+func TestFindFieldDescriptorIndexed(t *testing.T) {
+	initProtoForTest(t)
+	msgDesc := FindMessageDescriptor("QuestCfg")
+	tests := []struct {
+		name      string
+		wantField string
+	}{
+		{"Rewards[0].CfgId", "gserver.AddElemArg.CfgId"},
+		{"Rewards[1]", "gserver.QuestCfg.Rewards"},
+		{"NextQuests[2]", "gserver.QuestCfg.NextQuests"},
+		{"Rewards[0].Properties.a", "gserver.AddElemArg.PropertiesEntry.value"},
+		{"Rewards.CfgId", ""},       // repeated字段展开必须带下标
+		{"CfgId[0]", ""},            // 非repeated字段不能使用下标
+		{"Properties[0]", ""},       // map字段不能使用下标
+		{"NextQuests[0].CfgId", ""}, // 非message字段不能展开
+	}
+	for _, tt := range tests {
+		fieldDesc := FindFieldDescriptor(msgDesc, tt.name)
+		got := ""
+		if fieldDesc != nil {
+			got = fieldDesc.GetFullyQualifiedName()
+		}
+		if got != tt.wantField {
+			t.Errorf("FindFieldDescriptor(%v)=%v want:%v", tt.name, got, tt.wantField)
+		}
+	}
+}
+
+func TestConvertSheetIndexedColumn(t *testing.T) {
+	initProtoForTest(t)
+	toJson := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	f := newTestSheetFile(t, "Quest",
+		[]interface{}{"CfgId", "Rewards[0].CfgId", "Rewards[0].Num", "Rewards[1].CfgId", "Rewards[1].Num", "NextQuests[0]", "NextQuests[1]"},
+		[]interface{}{"1", "10", "1", "11", "2", "2", "3"},
+		[]interface{}{"2", "", "", "12", "5", "", "4"},
+		[]interface{}{"3"},
+	)
+	defer func() { _ = f.Close() }()
+	diags := NewDiagnostics()
+	opt := &SheetOption{SheetName: "Quest", MessageName: "QuestCfg", MgrType: "slice"}
+	data, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), opt, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags.Items()) > 0 {
+		t.Fatalf("unexpected diags: %v", diags.Items()[0])
+	}
+	// 所有列都为空的元素跳过
+	want := `[{"CfgId":1,"NextQuests":[2,3],"Rewards":[{"CfgId":10,"Num":1},{"CfgId":11,"Num":2}]},` +
+		`{"CfgId":2,"NextQuests":[4],"Rewards":[{"CfgId":12,"Num":5}]},` +
+		`{"CfgId":3}]`
+	if got := toJson(data); got != want {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+	if _, err = marshalToProtoBinary(data, opt); err != nil {
+		t.Fatal(err)
+	}
+
+	// object格式
+	f2 := newTestSheetFile(t, "QuestObj",
+		[]interface{}{"key", "value"},
+		[]interface{}{"CfgId", "1"},
+		[]interface{}{"Rewards[1].CfgId", "11"},
+		[]interface{}{"Rewards[0].CfgId", "10"},
+		[]interface{}{"Rewards[0].Num", "2"},
+		[]interface{}{"NextQuests[0]", "5"},
+	)
+	defer func() { _ = f2.Close() }()
+	objOpt := &SheetOption{SheetName: "QuestObj", MessageName: "QuestCfg", MgrType: "object"}
+	data, err = ConvertSourceSheet(&ExportOption{}, NewExcelSource(f2), objOpt, NewDiagnostics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"CfgId":1,"NextQuests":[5],"Rewards":[{"CfgId":10,"Num":2},{"CfgId":11}]}`
+	if got := toJson(data); got != want {
+		t.Fatalf("object got:\n%v\nwant:\n%v", got, want)
+	}
+	if _, err = marshalToProtoBinary(data, objOpt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRefsIndexedColumn(t *testing.T) {
+	initProtoForTest(t)
+	exportInfoMap := map[string]*ExportInfo{
+		"item.Item": {
+			SheetOption: &SheetOption{SheetName: "Item", MessageName: "ItemCfg", MgrType: "map", MapKeyName: "CfgId"},
+			MgrData: map[int32]any{
+				10: map[string]any{"CfgId": int32(10)},
+			},
+		},
+		"quest.Quest": {
+			SheetOption: &SheetOption{
+				SheetName:   "Quest",
+				MessageName: "QuestCfg",
+				MgrType:     "slice",
+				ColumnOpts: []*ColumnOption{
+					{Name: "Rewards[0].CfgId", Ref: "Item", Indexed: true},
+					{Name: "Rewards[1].CfgId", Ref: "Item", Indexed: true},
+					{Name: "NextQuests[0]", Ref: "Quest.CfgId", Indexed: true},
+				},
+			},
+			MgrData: []any{
+				map[string]any{
+					"CfgId":      int32(1),
+					"Rewards":    []any{map[string]any{"CfgId": int32(10)}, map[string]any{"CfgId": int32(9)}},
+					"NextQuests": []any{int32(1), int32(7)},
+				},
+			},
+		},
+	}
+	orderNames := []string{"item.Item", "quest.Quest"}
+	refCheckMap := make(map[string]*ExportInfo)
+	for _, name := range orderNames {
+		exportInfo := exportInfoMap[name]
+		refCheckMap[exportInfo.SheetOption.SheetName] = exportInfo
+	}
+	diags := NewDiagnostics()
+	checkRefs(exportInfoMap, orderNames, refCheckMap, diags)
+	var got []string
+	for _, diag := range diags.Items() {
+		got = append(got, diag.SheetName+" "+diag.Column+" "+diag.Message)
+	}
+	want := []string{
+		"Quest Rewards.CfgId ref ERROR ref:Item checkId:9 row:0",
+		"Quest NextQuests ref ERROR ref:Quest.CfgId checkId:7 row:0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
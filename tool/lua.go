@@ -0,0 +1,330 @@
+package tool
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// lua的关键字不能直接作为table的key
+var luaKeywords = map[string]struct{}{
+	"and": {}, "break": {}, "do": {}, "else": {}, "elseif": {}, "end": {},
+	"false": {}, "for": {}, "function": {}, "goto": {}, "if": {}, "in": {},
+	"local": {}, "nil": {}, "not": {}, "or": {}, "repeat": {}, "return": {},
+	"then": {}, "true": {}, "until": {}, "while": {},
+}
+
+// 导出为lua代码: return { ... }
+// map: return { [key] = row, ... } key按顺序排列
+// group: return { [key] = { row, ... }, ... } key按顺序排列
+// slice: return { row, ... }
+// object: return row
+// 每一行数据按proto的定义转换,整数和浮点数按字段类型区分
+func marshalToLua(v any, sheetOption *SheetOption) ([]byte, error) {
+	msgDesc := FindMessageDescriptor(sheetOption.MessageName)
+	if msgDesc == nil {
+		return nil, fmt.Errorf("message %s not found", sheetOption.MessageName)
+	}
+	msgType := msgDesc.UnwrapMessage()
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString("return ")
+	switch sheetOption.MgrType {
+	case "map":
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map {
+			return nil, fmt.Errorf("invalid map data type: %T", v)
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessMapKey(keys[i], keys[j])
+		})
+		buffer.WriteString("{\n")
+		for _, key := range keys {
+			msg, err := toDynamicProtoMessage(msgType, rv.MapIndex(key).Interface())
+			if err != nil {
+				return nil, err
+			}
+			writeLuaIndent(buffer, 1)
+			buffer.WriteString("[")
+			writeLuaScalar(buffer, key.Interface())
+			buffer.WriteString("] = ")
+			writeLuaMessage(buffer, msg.ProtoReflect(), 1)
+			buffer.WriteString(",\n")
+		}
+		buffer.WriteString("}\n")
+	case "group":
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map {
+			return nil, fmt.Errorf("invalid group data type: %T", v)
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessMapKey(keys[i], keys[j])
+		})
+		buffer.WriteString("{\n")
+		for _, key := range keys {
+			groupRows, ok := rv.MapIndex(key).Interface().([]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid group data type: %T", rv.MapIndex(key).Interface())
+			}
+			writeLuaIndent(buffer, 1)
+			buffer.WriteString("[")
+			writeLuaScalar(buffer, key.Interface())
+			buffer.WriteString("] = {\n")
+			for _, row := range groupRows {
+				msg, err := toDynamicProtoMessage(msgType, row)
+				if err != nil {
+					return nil, err
+				}
+				writeLuaIndent(buffer, 2)
+				writeLuaMessage(buffer, msg.ProtoReflect(), 2)
+				buffer.WriteString(",\n")
+			}
+			writeLuaIndent(buffer, 1)
+			buffer.WriteString("},\n")
+		}
+		buffer.WriteString("}\n")
+	case "slice":
+		dataSlice, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid slice data type: %T", v)
+		}
+		buffer.WriteString("{\n")
+		for _, row := range dataSlice {
+			msg, err := toDynamicProtoMessage(msgType, row)
+			if err != nil {
+				return nil, err
+			}
+			writeLuaIndent(buffer, 1)
+			writeLuaMessage(buffer, msg.ProtoReflect(), 1)
+			buffer.WriteString(",\n")
+		}
+		buffer.WriteString("}\n")
+	case "object":
+		msg, err := toDynamicProtoMessage(msgType, v)
+		if err != nil {
+			return nil, err
+		}
+		writeLuaMessage(buffer, msg.ProtoReflect(), 0)
+		buffer.WriteString("\n")
+	default:
+		return nil, fmt.Errorf("unsupported MgrType: %s", sheetOption.MgrType)
+	}
+	return buffer.Bytes(), nil
+}
+
+func lessMapKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	default:
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	}
+}
+
+func writeLuaIndent(buffer *bytes.Buffer, indent int) {
+	for i := 0; i < indent; i++ {
+		buffer.WriteString("  ")
+	}
+}
+
+// 字段名是合法的lua标识符时直接作为key,否则写成["key"]
+func writeLuaFieldName(buffer *bytes.Buffer, name string) {
+	if isLuaIdentifier(name) {
+		buffer.WriteString(name)
+		return
+	}
+	buffer.WriteString("[")
+	buffer.WriteString(QuoteLuaString(name, false))
+	buffer.WriteString("]")
+}
+
+func isLuaIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	if _, ok := luaKeywords[name]; ok {
+		return false
+	}
+	for i, c := range name {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			continue
+		}
+		if i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// 按字段定义的顺序输出
+// 没有设置的message,oneof和空的数组,map不输出,普通字段即使是默认值也会输出,避免lua中取到nil
+func writeLuaMessage(buffer *bytes.Buffer, msg protoreflect.Message, indent int) {
+	buffer.WriteString("{\n")
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.HasPresence() && !msg.Has(fd) {
+			continue
+		}
+		value := msg.Get(fd)
+		if fd.IsList() && value.List().Len() == 0 {
+			continue
+		}
+		if fd.IsMap() && value.Map().Len() == 0 {
+			continue
+		}
+		writeLuaIndent(buffer, indent+1)
+		writeLuaFieldName(buffer, fd.JSONName())
+		buffer.WriteString(" = ")
+		switch {
+		case fd.IsList():
+			writeLuaList(buffer, fd, value.List(), indent+1)
+		case fd.IsMap():
+			writeLuaMap(buffer, fd, value.Map(), indent+1)
+		default:
+			writeLuaValue(buffer, fd, value, indent+1)
+		}
+		buffer.WriteString(",\n")
+	}
+	writeLuaIndent(buffer, indent)
+	buffer.WriteString("}")
+}
+
+// 普通类型的数组写在一行,message数组每个元素一行
+func writeLuaList(buffer *bytes.Buffer, fd protoreflect.FieldDescriptor, list protoreflect.List, indent int) {
+	if fd.Message() == nil {
+		buffer.WriteString("{")
+		for i := 0; i < list.Len(); i++ {
+			if i > 0 {
+				buffer.WriteString(", ")
+			}
+			writeLuaValue(buffer, fd, list.Get(i), indent)
+		}
+		buffer.WriteString("}")
+		return
+	}
+	buffer.WriteString("{\n")
+	for i := 0; i < list.Len(); i++ {
+		writeLuaIndent(buffer, indent+1)
+		writeLuaValue(buffer, fd, list.Get(i), indent+1)
+		buffer.WriteString(",\n")
+	}
+	writeLuaIndent(buffer, indent)
+	buffer.WriteString("}")
+}
+
+func writeLuaMap(buffer *bytes.Buffer, fd protoreflect.FieldDescriptor, m protoreflect.Map, indent int) {
+	keys := make([]protoreflect.MapKey, 0, m.Len())
+	m.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		return lessMapKey(reflect.ValueOf(keys[i].Interface()), reflect.ValueOf(keys[j].Interface()))
+	})
+	valueDesc := fd.MapValue()
+	buffer.WriteString("{\n")
+	for _, key := range keys {
+		writeLuaIndent(buffer, indent+1)
+		buffer.WriteString("[")
+		writeLuaScalar(buffer, key.Interface())
+		buffer.WriteString("] = ")
+		writeLuaValue(buffer, valueDesc, m.Get(key), indent+1)
+		buffer.WriteString(",\n")
+	}
+	writeLuaIndent(buffer, indent)
+	buffer.WriteString("}")
+}
+
+func writeLuaValue(buffer *bytes.Buffer, fd protoreflect.FieldDescriptor, value protoreflect.Value, indent int) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		writeLuaMessage(buffer, value.Message(), indent)
+	case protoreflect.EnumKind:
+		buffer.WriteString(strconv.FormatInt(int64(value.Enum()), 10))
+	case protoreflect.FloatKind:
+		buffer.WriteString(FormatLuaFloat(value.Float(), 32))
+	case protoreflect.DoubleKind:
+		buffer.WriteString(FormatLuaFloat(value.Float(), 64))
+	case protoreflect.BytesKind:
+		buffer.WriteString(QuoteLuaString(string(value.Bytes()), true))
+	default:
+		writeLuaScalar(buffer, value.Interface())
+	}
+}
+
+func writeLuaScalar(buffer *bytes.Buffer, v any) {
+	switch t := v.(type) {
+	case string:
+		buffer.WriteString(QuoteLuaString(t, false))
+	case bool:
+		buffer.WriteString(strconv.FormatBool(t))
+	case float32:
+		buffer.WriteString(FormatLuaFloat(float64(t), 32))
+	case float64:
+		buffer.WriteString(FormatLuaFloat(t, 64))
+	default:
+		buffer.WriteString(fmt.Sprint(t))
+	}
+}
+
+// 浮点数总是带小数点,lua5.3以上可以用math.type区分整数和浮点数
+func FormatLuaFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "math.huge"
+	case math.IsInf(f, -1):
+		return "-math.huge"
+	case math.IsNaN(f):
+		return "(0/0)"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// 转换成双引号的lua字符串
+// 控制字符使用\ddd转义,escapeNonASCII为true时非ascii字符也转义(用于bytes)
+func QuoteLuaString(s string, escapeNonASCII bool) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f || (escapeNonASCII && c >= 0x80) {
+				// 固定3位,避免和后面的数字连在一起
+				sb.WriteString(fmt.Sprintf("\\%03d", c))
+			} else {
+				sb.WriteByte(c)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,102 @@
+package tool
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuoteLuaString(t *testing.T) {
+	tests := []struct {
+		input          string
+		escapeNonASCII bool
+		want           string
+	}{
+		{"abc", false, `"abc"`},
+		{"a\"b\\c", false, `"a\"b\\c"`},
+		{"line1\nline2\t\r", false, `"line1\nline2\t\r"`},
+		{"\x001", false, `"\0001"`},
+		{"物品", false, `"物品"`},
+		{"\xff\x01", true, `"\255\001"`},
+	}
+	for _, tt := range tests {
+		if got := QuoteLuaString(tt.input, tt.escapeNonASCII); got != tt.want {
+			t.Errorf("input:%q expected %v, got %v", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestFormatLuaFloat(t *testing.T) {
+	tests := []struct {
+		input   float64
+		bitSize int
+		want    string
+	}{
+		{1, 64, "1.0"},
+		{1.5, 32, "1.5"},
+		{float64(float32(0.1)), 32, "0.1"},
+		{-2.25, 64, "-2.25"},
+		{1e21, 64, "1e+21"},
+		{math.Inf(1), 64, "math.huge"},
+		{math.Inf(-1), 64, "-math.huge"},
+	}
+	for _, tt := range tests {
+		if got := FormatLuaFloat(tt.input, tt.bitSize); got != tt.want {
+			t.Errorf("input:%v expected %v, got %v", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestMarshalToLua(t *testing.T) {
+	initTempProtoForTest(t, "types_test.proto", typesTestProto)
+	row := func(cfgId int32) map[string]any {
+		return map[string]any{
+			"CfgId":       cfgId,
+			"Int64Value":  int64(-3000000000),
+			"Uint32Value": uint32(7),
+			"FloatValue":  float32(2),
+			"DoubleValue": 0.5,
+			"BoolValue":   true,
+			"ColorValue":  int32(1),
+			"Int32Values": []any{int32(1), int32(2)},
+		}
+	}
+	rowLua := func(cfgId string, indent string) string {
+		return "{\n" +
+			indent + "  CfgId = " + cfgId + ",\n" +
+			indent + "  Int64Value = -3000000000,\n" +
+			indent + "  Uint32Value = 7,\n" +
+			indent + "  Uint64Value = 0,\n" +
+			indent + "  FloatValue = 2.0,\n" +
+			indent + "  DoubleValue = 0.5,\n" +
+			indent + "  BoolValue = true,\n" +
+			indent + "  ColorValue = 1,\n" +
+			indent + "  Int32Values = {1, 2},\n" +
+			indent + "}"
+	}
+	tests := []struct {
+		mgrType string
+		data    any
+		want    string
+	}{
+		{"map", map[int32]any{2: row(2), 1: row(1)},
+			"return {\n  [1] = " + rowLua("1", "  ") + ",\n  [2] = " + rowLua("2", "  ") + ",\n}\n"},
+		{"map", map[string]any{"b": row(2), "a": row(1)},
+			"return {\n  [\"a\"] = " + rowLua("1", "  ") + ",\n  [\"b\"] = " + rowLua("2", "  ") + ",\n}\n"},
+		{"slice", []any{row(2), row(1)},
+			"return {\n  " + rowLua("2", "  ") + ",\n  " + rowLua("1", "  ") + ",\n}\n"},
+		{"object", row(3), "return " + rowLua("3", "") + "\n"},
+		{"slice", []any{}, "return {\n}\n"},
+	}
+	for _, tt := range tests {
+		got, err := marshalToLua(tt.data, &SheetOption{MessageName: "TestCfgTypes", MgrType: tt.mgrType})
+		if err != nil {
+			t.Fatalf("mgrType:%v err:%v", tt.mgrType, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("mgrType:%v expected:\n%v\ngot:\n%v", tt.mgrType, tt.want, string(got))
+		}
+	}
+	if _, err := marshalToLua([]any{row(1)}, &SheetOption{MessageName: "TestCfgTypes", MgrType: "map"}); err == nil {
+		t.Error("expected error for invalid map data")
+	}
+}
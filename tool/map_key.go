@@ -0,0 +1,170 @@
+package tool
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// 组合key的分隔符,如MapKey=ActivityId,Stage导出的key为"1_2"
+const compositeKeySep = "_"
+
+// 是否是多个字段组成的组合key,如MapKey=ActivityId,Stage
+func isCompositeMapKey(mapKeyName string) bool {
+	return strings.Contains(mapKeyName, ",")
+}
+
+// 组合key的字段名
+func splitCompositeMapKey(mapKeyName string) []string {
+	var names []string
+	for _, name := range strings.Split(mapKeyName, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// 查找组合key的字段,字段必须是int32 int64 uint32 uint64 string类型的非repeated字段
+func findCompositeKeyFields(msgDesc *desc.MessageDescriptor, mapKeyName string) ([]*desc.FieldDescriptor, error) {
+	var fieldDescs []*desc.FieldDescriptor
+	for _, name := range splitCompositeMapKey(mapKeyName) {
+		fieldDesc := FindFieldDescriptor(msgDesc, name)
+		if fieldDesc == nil {
+			return nil, fmt.Errorf("map key field not found:%v message:%v", name, msgDesc.GetName())
+		}
+		if strings.Contains(name, ".") || fieldDesc.IsRepeated() || fieldDesc.GetMessageType() != nil || GetKeyTypeString(fieldDesc) == "" {
+			return nil, fmt.Errorf("unsupported map key field type:%v message:%v", name, msgDesc.GetName())
+		}
+		fieldDescs = append(fieldDescs, fieldDesc)
+	}
+	if len(fieldDescs) < 2 {
+		return nil, fmt.Errorf("composite map key needs at least 2 fields:%v message:%v", mapKeyName, msgDesc.GetName())
+	}
+	return fieldDescs, nil
+}
+
+// 获取组合key的值,用compositeKeySep连接,如"1_2",有字段没有值时返回该字段的索引,否则返回-1
+func getCompositeKeyValue(rowValue map[string]any, mapKeyName string) (string, int) {
+	names := splitCompositeMapKey(mapKeyName)
+	values := make([]string, 0, len(names))
+	for i, name := range names {
+		value := rowValue[name]
+		if value == nil {
+			return "", i
+		}
+		s, _ := refValueString(value)
+		values = append(values, s)
+	}
+	return strings.Join(values, compositeKeySep), -1
+}
+
+// 和protoc-gen-go生成的字段名一致
+func goCamelCase(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isASCIILower(s[i+1]):
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isASCIILower(s[i+1]):
+		case isASCIIDigit(c):
+			b = append(b, c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isASCIILower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+func isASCIILower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isASCIIDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// 首字母小写,用于生成代码中的参数名
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// 按json名查找字段,支持展开的子字段,如Child.FieldName
+func findFieldDescriptorPath(msgDesc *desc.MessageDescriptor, jsonPath string) []*desc.FieldDescriptor {
+	var fieldDescs []*desc.FieldDescriptor
+	for _, name := range strings.Split(jsonPath, ".") {
+		if msgDesc == nil {
+			return nil
+		}
+		fieldDesc := msgDesc.FindFieldByJSONName(name)
+		if fieldDesc == nil {
+			fieldDesc = msgDesc.FindFieldByName(name)
+		}
+		if fieldDesc == nil {
+			return nil
+		}
+		fieldDescs = append(fieldDescs, fieldDesc)
+		msgDesc = fieldDesc.GetMessageType()
+	}
+	return fieldDescs
+}
+
+// 设置生成代码用的map key信息
+// usedKeyTypeNames:已经使用的组合key结构体名,同一个message有多个组合key的管理类时避免重名
+func setMapKeyInfo(mgrInfo *DataMgrInfo, msgDesc *desc.MessageDescriptor, mapKeyName string, usedKeyTypeNames map[string]struct{}) {
+	if msgDesc == nil || mapKeyName == "" {
+		return
+	}
+	var keyFields []*MapKeyField
+	for _, name := range splitCompositeMapKey(mapKeyName) {
+		fieldPath := findFieldDescriptorPath(msgDesc, name)
+		if len(fieldPath) == 0 {
+			color.Red("map key field not found:%v message:%v", name, msgDesc.GetName())
+			return
+		}
+		var getters []string
+		for _, fieldDesc := range fieldPath {
+			getters = append(getters, "Get"+goCamelCase(fieldDesc.GetName())+"()")
+		}
+		fieldDesc := fieldPath[len(fieldPath)-1]
+		keyField := &MapKeyField{
+			Name:   name,
+			GoName: goCamelCase(fieldDesc.GetName()),
+			GoType: GetKeyTypeString(fieldDesc),
+			Getter: strings.Join(getters, "."),
+		}
+		keyField.ArgName = lowerFirst(keyField.GoName)
+		if token.IsKeyword(keyField.ArgName) {
+			keyField.ArgName += "_"
+		}
+		keyFields = append(keyFields, keyField)
+	}
+	mgrInfo.MapKeyFields = keyFields
+	if len(keyFields) == 1 {
+		mgrInfo.MapKeyGoType = keyFields[0].GoType
+		return
+	}
+	keyTypeName := mgrInfo.MessageName + "Key"
+	if _, ok := usedKeyTypeNames[keyTypeName]; ok {
+		keyTypeName = mgrInfo.MgrName + "Key"
+	}
+	usedKeyTypeNames[keyTypeName] = struct{}{}
+	mgrInfo.MapKeyGoType = keyTypeName
+	mgrInfo.MapKeyGetFnName = "Get" + strings.TrimSuffix(keyTypeName, "Key")
+}
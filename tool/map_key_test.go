@@ -0,0 +1,120 @@
+package tool
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestConvertSheetCompositeKey(t *testing.T) {
+	initProtoForTest(t)
+	f := newTestSheetFile(t, "ItemNum",
+		[]interface{}{"CfgId", "Num"},
+		[]interface{}{"1", "10"},
+		[]interface{}{"1", "2"},
+		[]interface{}{"2", ""},
+		[]interface{}{"1", "10"},
+	)
+	defer func() { _ = f.Close() }()
+	diags := NewDiagnostics()
+	data, resultOpt, err := convertSheet(&ExportOption{}, NewExcelSource(f),
+		&SheetOption{ExcelName: "item.xlsx", SheetName: "ItemNum", MessageName: "ItemNum", MgrType: "map", MapKeyName: "CfgId, Num"},
+		FindMessageDescriptor("ItemNum"), diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resultOpt.MapKeyName != "CfgId,Num" || resultOpt.MapKeyType != "string" {
+		t.Fatalf("unexpected key: %v %v", resultOpt.MapKeyName, resultOpt.MapKeyType)
+	}
+	dataMap := data.(map[string]any)
+	if len(dataMap) != 2 || dataMap["1_10"] == nil || dataMap["1_2"] == nil {
+		t.Fatalf("unexpected data: %v", dataMap)
+	}
+	var got []string
+	for _, diag := range diags.Items() {
+		got = append(got, diag.Cell+" "+diag.Message)
+	}
+	want := []string{
+		"B4 key Num not found",
+		"A5 duplicate key 1_10, previous at A2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+
+	// pb格式按key排序导出
+	pbData, err := marshalToProtoBinary(data, resultOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgType := FindMessageDescriptor("ItemNum").UnwrapMessage()
+	reader := bytes.NewReader(pbData)
+	var nums []int64
+	for {
+		msg := dynamicpb.NewMessage(msgType)
+		if err = protodelim.UnmarshalFrom(reader, msg); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		nums = append(nums, msg.Get(msgType.Fields().ByName("Num")).Int())
+	}
+	if !reflect.DeepEqual(nums, []int64{10, 2}) {
+		t.Fatalf("unexpected pb rows: %v", nums)
+	}
+
+	// 组合key的表不能直接关联
+	refCheckMap := map[string]*ExportInfo{"ItemNum": {MgrData: data, SheetOption: resultOpt}}
+	if _, err = newRefTarget("ItemNum", refCheckMap); err == nil {
+		t.Fatal("expected composite key ref error")
+	}
+	if _, err = newRefTarget("ItemNum.CfgId", refCheckMap); err != nil {
+		t.Fatal(err)
+	}
+
+	// 不支持的key字段
+	if _, _, err = convertSheet(&ExportOption{}, NewExcelSource(f),
+		&SheetOption{SheetName: "ItemNum", MessageName: "TestCfgArgValues", MgrType: "map", MapKeyName: "CfgId,ArgValues"},
+		FindMessageDescriptor("TestCfgArgValues"), NewDiagnostics()); err == nil {
+		t.Fatal("expected unsupported key field error")
+	}
+}
+
+func TestSetMapKeyInfo(t *testing.T) {
+	initProtoForTest(t)
+	used := make(map[string]struct{})
+	info := &DataMgrInfo{MessageName: "ConditionTemplateCfg", MgrName: "ConditionTemplateCfgs", MgrType: "map"}
+	setMapKeyInfo(info, FindMessageDescriptor("ConditionTemplateCfg"), "Type,Key", used)
+	if !info.IsCompositeKey() || info.MapKeyGoType != "ConditionTemplateCfgKey" || info.MapKeyGetFnName != "GetConditionTemplateCfg" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	want := []MapKeyField{
+		{Name: "Type", GoName: "Type", GoType: "int32", ArgName: "type_", Getter: "GetType()"},
+		{Name: "Key", GoName: "Key", GoType: "string", ArgName: "key", Getter: "GetKey()"},
+	}
+	for i, field := range info.MapKeyFields {
+		if *field != want[i] {
+			t.Fatalf("unexpected field: %+v", field)
+		}
+	}
+	// 同一个message的另一个组合key的表
+	info2 := &DataMgrInfo{MessageName: "ConditionTemplateCfg", MgrName: "ConditionKeys", MgrType: "map"}
+	setMapKeyInfo(info2, FindMessageDescriptor("ConditionTemplateCfg"), "Key,Type", used)
+	if info2.MapKeyGoType != "ConditionKeysKey" || info2.MapKeyGetFnName != "GetConditionKeys" {
+		t.Fatalf("unexpected info: %+v", info2)
+	}
+	info3 := &DataMgrInfo{MessageName: "ItemCfg", MgrName: "ItemCfgs", MgrType: "map"}
+	setMapKeyInfo(info3, FindMessageDescriptor("ItemCfg"), "Name", used)
+	if info3.IsCompositeKey() || info3.MapKeyGoType != "string" || info3.MapKeyFields[0].Getter != "GetName()" {
+		t.Fatalf("unexpected info: %+v", info3)
+	}
+	for s, want := range map[string]string{"CfgId": "CfgId", "cfg_id": "CfgId", "item_type2": "ItemType2", "_x": "XX"} {
+		if got := goCamelCase(s); got != want {
+			t.Fatalf("goCamelCase(%v)=%v want:%v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package tool
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// 多行格式:key列为空的行是上一行的续行,续行的repeated字段追加到上一行的数据中
+//
+//	--------------------------------------------------------------
+//	| CfgId | Name  | Conditions.Type | Conditions.Key | Conditions.Values |
+//	--------------------------------------------------------------
+//	| 1     | quest | 1               | Level          | 10                |
+//	|       |       | 2               | Exp            | 100               |
+//	--------------------------------------------------------------
+const layoutMultiRow = "multirow"
+
+// 把repeated的message字段的展开列转换成带下标的列,如Conditions.Type转换成Conditions[0].Type
+// 每一行只配置一个元素,续行的元素再追加到数组中
+func normalizeMultiRowColumnName(msgDesc *desc.MessageDescriptor, name string) string {
+	segments, ok := parseColumnPath(name)
+	if !ok || len(segments) < 2 {
+		return name
+	}
+	changed := false
+	childMsgDesc := msgDesc
+	for i := 0; i < len(segments)-1 && childMsgDesc != nil; i++ {
+		fieldDesc := childMsgDesc.FindFieldByName(segments[i].Name)
+		if fieldDesc == nil {
+			fieldDesc = childMsgDesc.FindFieldByJSONName(segments[i].Name)
+		}
+		if fieldDesc == nil || fieldDesc.IsMap() || fieldDesc.GetType() != descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+			break
+		}
+		if fieldDesc.IsRepeated() && segments[i].Index < 0 {
+			segments[i].Index = 0
+			changed = true
+		}
+		childMsgDesc = fieldDesc.GetMessageType()
+	}
+	if !changed {
+		return name
+	}
+	return formatColumnPath(segments)
+}
+
+// 多行格式中用来判断续行的key列,map和group是key字段所在的列,slice是第一列
+func getMultiRowKeyColumns(msgDesc *desc.MessageDescriptor, opt *SheetOption, mapKeyFieldDesc *desc.FieldDescriptor, compositeKeyFieldDescs []*desc.FieldDescriptor) []*ColumnOption {
+	if !isKeyedMgrType(opt.MgrType) {
+		if len(opt.ColumnOpts) == 0 {
+			return nil
+		}
+		return opt.ColumnOpts[:1]
+	}
+	keyFieldDescs := compositeKeyFieldDescs
+	if keyFieldDescs == nil {
+		keyFieldDescs = []*desc.FieldDescriptor{mapKeyFieldDesc}
+	}
+	var keyColumns []*ColumnOption
+	for _, keyFieldDesc := range keyFieldDescs {
+		keyColumn := findColumnOptionByField(msgDesc, opt.ColumnOpts, keyFieldDesc)
+		if keyColumn == nil {
+			return nil
+		}
+		keyColumns = append(keyColumns, keyColumn)
+	}
+	return keyColumns
+}
+
+// key列都为空的行是续行
+func isMultiRowContinuation(row []string, keyColumns []*ColumnOption) bool {
+	if len(keyColumns) == 0 {
+		return false
+	}
+	for _, keyColumn := range keyColumns {
+		if keyColumn.ColumnIndex < len(row) && strings.TrimSpace(row[keyColumn.ColumnIndex]) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// 把续行的数据合并到上一行:repeated字段追加元素,子message逐个字段合并,
+// 其他字段只能配置一次,上一行已经有值时报错
+func mergeContinuationRow(ctx *CellContext, msgDesc *desc.MessageDescriptor, columnOpts []*ColumnOption, lastRowValue, rowValue map[string]any) {
+	mergeContinuationMessage(ctx, msgDesc, columnOpts, "", lastRowValue, rowValue)
+}
+
+func mergeContinuationMessage(ctx *CellContext, msgDesc *desc.MessageDescriptor, columnOpts []*ColumnOption, prefix string, dst, src map[string]any) {
+	names := make([]string, 0, len(src))
+	for name := range src {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := src[name]
+		oldValue, ok := dst[name]
+		if !ok {
+			dst[name] = value
+			continue
+		}
+		fieldDesc := FindFieldDescriptor(msgDesc, name)
+		if fieldDesc == nil {
+			continue
+		}
+		if fieldDesc.IsRepeated() && !fieldDesc.IsMap() {
+			oldList, ok1 := oldValue.([]any)
+			list, ok2 := value.([]any)
+			if ok1 && ok2 {
+				dst[name] = append(oldList, list...)
+				continue
+			}
+		} else if subMsgDesc := fieldDesc.GetMessageType(); subMsgDesc != nil && !fieldDesc.IsMap() {
+			oldChild, ok1 := oldValue.(map[string]any)
+			child, ok2 := value.(map[string]any)
+			if ok1 && ok2 {
+				mergeContinuationMessage(ctx, subMsgDesc, columnOpts, prefix+name+".", oldChild, child)
+				continue
+			}
+		}
+		ctx.Column = findColumnOptionByPath(columnOpts, prefix+name)
+		ctx.Errorf("continuation row field %v already set", prefix+name)
+	}
+}
+
+// 查找字段所在的列,用于诊断信息,如Progress.Type,没有完全匹配的列时返回第一层字段所在的列
+func findColumnOptionByPath(columnOpts []*ColumnOption, path string) *ColumnOption {
+	rootName, _, _ := strings.Cut(path, ".")
+	var rootColumn *ColumnOption
+	for _, columnOpt := range columnOpts {
+		name := stripColumnIndex(columnOpt.Name)
+		if name == path || strings.HasPrefix(name, path+".") {
+			return columnOpt
+		}
+		if rootColumn == nil && (name == rootName || strings.HasPrefix(name, rootName+".")) {
+			rootColumn = columnOpt
+		}
+	}
+	return rootColumn
+}
@@ -0,0 +1,107 @@
+package tool
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeMultiRowColumnName(t *testing.T) {
+	initProtoForTest(t)
+	msgDesc := FindMessageDescriptor("QuestCfg")
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Conditions.Type", "Conditions[0].Type"},
+		{"Conditions[1].Type", "Conditions[1].Type"},
+		{"Progress.Type", "Progress.Type"},
+		{"Progress.IntEventFields.Level.Op", "Progress.IntEventFields.Level.Op"},
+		{"NextQuests", "NextQuests"},
+	}
+	for _, tt := range tests {
+		if got := normalizeMultiRowColumnName(msgDesc, tt.name); got != tt.want {
+			t.Errorf("normalizeMultiRowColumnName(%v)=%v want:%v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConvertSheetMultiRow(t *testing.T) {
+	initProtoForTest(t)
+	toJson := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	f := newTestSheetFile(t, "Quest",
+		[]interface{}{"CfgId", "Name", "Conditions.Type", "Conditions.Key", "Conditions.Values", "NextQuests", "Progress.Type"},
+		[]interface{}{"1", "a", "1", "Level", "10", "2", "3"},
+		[]interface{}{"", "", "2", "Exp", "100;200", "3"},
+		[]interface{}{"#", "注释行"},
+		[]interface{}{"", "", "3"},
+		[]interface{}{"2", "b"},
+		[]interface{}{"", "", "4", "Vip", "1"},
+	)
+	defer func() { _ = f.Close() }()
+	for _, mgrType := range []string{"map", "slice", "group"} {
+		diags := NewDiagnostics()
+		opt := &SheetOption{SheetName: "Quest", MessageName: "QuestCfg", MgrType: mgrType, Layout: "multirow"}
+		data, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), opt, diags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(diags.Items()) > 0 {
+			t.Fatalf("%v unexpected diags: %v", mgrType, diags.Items()[0])
+		}
+		row1 := `{"CfgId":1,"Conditions":[{"Key":"Level","Type":1,"Values":[10]},{"Key":"Exp","Type":2,"Values":[100,200]},{"Type":3}],"Name":"a","NextQuests":[2,3],"Progress":{"Type":3}}`
+		row2 := `{"CfgId":2,"Conditions":[{"Key":"Vip","Type":4,"Values":[1]}],"Name":"b","Progress":{}}`
+		want := `[` + row1 + `,` + row2 + `]`
+		switch mgrType {
+		case "map":
+			want = `{"1":` + row1 + `,"2":` + row2 + `}`
+		case "group":
+			want = `{"1":[` + row1 + `],"2":[` + row2 + `]}`
+		}
+		if got := toJson(data); got != want {
+			t.Fatalf("%v got:\n%v\nwant:\n%v", mgrType, got, want)
+		}
+		if _, err = marshalToProtoBinary(data, opt); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConvertSheetMultiRowErrors(t *testing.T) {
+	initProtoForTest(t)
+	f := newTestSheetFile(t, "Quest",
+		[]interface{}{"CfgId", "Name", "Conditions.Type", "Progress.Type"},
+		[]interface{}{"", "x", "1"},
+		[]interface{}{"1", "a", "1", "3"},
+		[]interface{}{"", "b", "2", "4"},
+	)
+	defer func() { _ = f.Close() }()
+	diags := NewDiagnostics()
+	opt := &SheetOption{SheetName: "Quest", MessageName: "QuestCfg", MgrType: "slice", Layout: "multirow"}
+	if _, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), opt, diags); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, diag := range diags.Items() {
+		got = append(got, diag.Cell+" "+diag.Message)
+	}
+	want := []string{
+		"A2 continuation row has no previous row",
+		"B4 continuation row field Name already set",
+		"D4 continuation row field Progress.Type already set",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+
+	opt = &SheetOption{SheetName: "Quest", MessageName: "QuestCfg", MgrType: "slice", Layout: "unknown"}
+	if _, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), opt, NewDiagnostics()); err == nil {
+		t.Fatal("expect unsupported Layout error")
+	}
+}
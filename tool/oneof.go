@@ -0,0 +1,197 @@
+package tool
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// oneof的处理:
+// 一个oneof只能配置一个字段,配置了多个字段时报错,并且只保留第一个字段,避免转换成proto时报错;
+// 列名是oneof名的列是Kind列,单元格填字段名,指定使用oneof中的哪个字段:
+//
+//	------------------------------------------
+//	| CfgId | Target | Item.CfgId | Gold |
+//	------------------------------------------
+//	| 1     | Gold   |            | 100  |
+//	| 2     | None   |            |      |
+//	------------------------------------------
+//
+// Kind列指定的字段没有配置值时,导出为默认值,如空的message,适合只用来区分类型的oneof字段
+
+var _messageHasOneOfCache sync.Map // *desc.MessageDescriptor -> bool
+
+// 查找message中的oneof,不包括proto3 optional字段自动生成的oneof
+func findOneOfDescriptor(msgDesc *desc.MessageDescriptor, name string) *desc.OneOfDescriptor {
+	for _, oneOf := range msgDesc.GetOneOfs() {
+		if oneOf.IsSynthetic() {
+			continue
+		}
+		if oneOf.GetName() == name {
+			return oneOf
+		}
+	}
+	return nil
+}
+
+// 查找oneof中的字段,支持字段名和json名
+func findOneOfChoice(oneOf *desc.OneOfDescriptor, name string) *desc.FieldDescriptor {
+	for _, fieldDesc := range oneOf.GetChoices() {
+		if fieldDesc.GetName() == name || fieldDesc.GetJSONName() == name {
+			return fieldDesc
+		}
+	}
+	return nil
+}
+
+// 处理一行数据中的oneof,kinds是Kind列的值(oneof名->字段名)
+func applyOneOfs(ctx *CellContext, msgDesc *desc.MessageDescriptor, columnOpts []*ColumnOption, kinds map[string]string, rowValue map[string]any) {
+	var choices []*desc.FieldDescriptor
+	for _, oneOf := range msgDesc.GetOneOfs() {
+		if oneOf.IsSynthetic() {
+			continue
+		}
+		kind := kinds[oneOf.GetName()]
+		if kind == "" {
+			continue
+		}
+		ctx.Column = findColumnOptionByPath(columnOpts, oneOf.GetName())
+		choice := findOneOfChoice(oneOf, kind)
+		if choice == nil {
+			ctx.Errorf("oneof %v has no field %v", oneOf.GetName(), kind)
+			continue
+		}
+		for _, fieldDesc := range oneOf.GetChoices() {
+			if fieldDesc == choice {
+				continue
+			}
+			if value, ok := rowValue[fieldDesc.GetJSONName()]; ok {
+				delete(rowValue, fieldDesc.GetJSONName())
+				if !isEmptyOneOfValue(value) {
+					ctx.Column = findColumnOptionByPath(columnOpts, fieldDesc.GetJSONName())
+					ctx.Errorf("oneof %v kind is %v but %v is set", oneOf.GetName(), kind, fieldDesc.GetName())
+				}
+			}
+		}
+		choices = append(choices, choice)
+	}
+	checkOneOfFields(ctx, msgDesc, columnOpts, "", rowValue)
+	for _, choice := range choices {
+		if value, ok := rowValue[choice.GetJSONName()]; !ok || isEmptyOneOfValue(value) {
+			rowValue[choice.GetJSONName()] = getOneOfDefaultValue(choice)
+		}
+	}
+}
+
+// object格式的数据,所有行组成一条数据,Kind列是key为oneof名的行
+func applyOneOfsOfObject(diags *Diagnostics, opt *SheetOption, msgDesc *desc.MessageDescriptor, kinds map[string]string, m map[any]any) map[any]any {
+	if len(kinds) == 0 && !messageHasOneOf(msgDesc) {
+		return m
+	}
+	rowValue := make(map[string]any, len(m))
+	for k, v := range m {
+		rowValue[k.(string)] = v
+	}
+	ctx := &CellContext{
+		Diags:     diags,
+		ExcelName: opt.ExcelName,
+		SheetName: opt.SheetName,
+	}
+	applyOneOfs(ctx, msgDesc, nil, kinds, rowValue)
+	newM := make(map[any]any, len(rowValue))
+	for k, v := range rowValue {
+		newM[k] = v
+	}
+	return newM
+}
+
+// 检查message及其子message中的oneof只配置了一个字段,多余的字段报错后删除
+// 展开列自动创建的空的子message不算配置了字段
+func checkOneOfFields(ctx *CellContext, msgDesc *desc.MessageDescriptor, columnOpts []*ColumnOption, prefix string, m map[string]any) {
+	if !messageHasOneOf(msgDesc) {
+		return
+	}
+	for _, oneOf := range msgDesc.GetOneOfs() {
+		if oneOf.IsSynthetic() {
+			continue
+		}
+		var setNames []string
+		for _, fieldDesc := range oneOf.GetChoices() {
+			value, ok := m[fieldDesc.GetJSONName()]
+			if !ok {
+				continue
+			}
+			if isEmptyOneOfValue(value) {
+				delete(m, fieldDesc.GetJSONName())
+				continue
+			}
+			setNames = append(setNames, fieldDesc.GetName())
+			if len(setNames) > 1 {
+				delete(m, fieldDesc.GetJSONName())
+			}
+		}
+		if len(setNames) > 1 {
+			ctx.Column = findColumnOptionByPath(columnOpts, prefix+setNames[1])
+			ctx.Errorf("oneof %v%v has multiple fields set: %v", prefix, oneOf.GetName(), strings.Join(setNames, ","))
+		}
+	}
+	for _, fieldDesc := range msgDesc.GetFields() {
+		subMsgDesc := fieldDesc.GetMessageType()
+		if subMsgDesc == nil || fieldDesc.IsMap() {
+			continue
+		}
+		switch v := m[fieldDesc.GetJSONName()].(type) {
+		case map[string]any:
+			checkOneOfFields(ctx, subMsgDesc, columnOpts, prefix+fieldDesc.GetJSONName()+".", v)
+		case []any:
+			for _, elem := range v {
+				if elemMap, ok := elem.(map[string]any); ok {
+					checkOneOfFields(ctx, subMsgDesc, columnOpts, prefix+fieldDesc.GetJSONName()+".", elemMap)
+				}
+			}
+		}
+	}
+}
+
+// message及其子message(不包括map的value)中是否有oneof
+func messageHasOneOf(msgDesc *desc.MessageDescriptor) bool {
+	if v, ok := _messageHasOneOfCache.Load(msgDesc); ok {
+		return v.(bool)
+	}
+	has := checkMessageHasOneOf(msgDesc, make(map[*desc.MessageDescriptor]struct{}))
+	_messageHasOneOfCache.Store(msgDesc, has)
+	return has
+}
+
+func checkMessageHasOneOf(msgDesc *desc.MessageDescriptor, visited map[*desc.MessageDescriptor]struct{}) bool {
+	if _, ok := visited[msgDesc]; ok {
+		return false
+	}
+	visited[msgDesc] = struct{}{}
+	for _, oneOf := range msgDesc.GetOneOfs() {
+		if !oneOf.IsSynthetic() {
+			return true
+		}
+	}
+	for _, fieldDesc := range msgDesc.GetFields() {
+		if subMsgDesc := fieldDesc.GetMessageType(); subMsgDesc != nil && !fieldDesc.IsMap() && checkMessageHasOneOf(subMsgDesc, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// 展开列自动创建的空的子message
+func isEmptyOneOfValue(value any) bool {
+	m, ok := value.(map[string]any)
+	return ok && len(m) == 0
+}
+
+// Kind列指定的字段没有配置值时的默认值
+func getOneOfDefaultValue(fieldDesc *desc.FieldDescriptor) any {
+	if fieldDesc.GetMessageType() != nil {
+		return make(map[string]any)
+	}
+	return fieldDesc.GetDefaultValue()
+}
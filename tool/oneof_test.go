@@ -0,0 +1,116 @@
+package tool
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const oneOfTestProto = `syntax = "proto3";
+package oneoftest;
+
+message OneOfItem {
+  int32 CfgId = 1;
+  int32 Num = 2;
+}
+
+message OneOfEmpty {
+}
+
+message OneOfSub {
+  oneof Value {
+    int32 IntValue = 1;
+    string StrValue = 2;
+  }
+}
+
+message OneOfCfg {
+  int32 CfgId = 1;
+  oneof Target {
+    OneOfItem Item = 2;
+    int32 Gold = 3;
+    OneOfEmpty None = 4;
+  }
+  OneOfSub Sub = 5;
+  optional int32 Level = 6;
+}
+`
+
+func TestConvertSheetOneOf(t *testing.T) {
+	initTempProtoForTest(t, "oneof_test.proto", oneOfTestProto)
+	toJson := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	f := newTestSheetFile(t, "OneOf",
+		[]interface{}{"CfgId", "Target", "Item.CfgId", "Item.Num", "Gold", "Sub.IntValue", "Sub.StrValue", "Level"},
+		[]interface{}{"1", "Gold", "", "", "100"},
+		[]interface{}{"2", "None"},
+		[]interface{}{"3", "", "5", "1", "", "", "", "0"},
+		[]interface{}{"4", "", "2", "", "1"},
+		[]interface{}{"5", "Gold", "3"},
+		[]interface{}{"6", "Bad"},
+		[]interface{}{"7", "", "", "", "", "1", "a"},
+	)
+	defer func() { _ = f.Close() }()
+	diags := NewDiagnostics()
+	opt := &SheetOption{SheetName: "OneOf", MessageName: "OneOfCfg", MgrType: "slice"}
+	data, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), opt, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"CfgId":1,"Gold":100,"Sub":{}},` +
+		`{"CfgId":2,"None":{},"Sub":{}},` +
+		`{"CfgId":3,"Item":{"CfgId":5,"Num":1},"Level":0,"Sub":{}},` +
+		`{"CfgId":4,"Item":{"CfgId":2},"Sub":{}},` +
+		`{"CfgId":5,"Gold":0,"Sub":{}},` +
+		`{"CfgId":6,"Sub":{}},` +
+		`{"CfgId":7,"Sub":{"IntValue":1}}]`
+	if got := toJson(data); got != want {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+	// 多个字段的oneof保留第一个字段,可以转换成proto
+	if _, err = marshalToProtoBinary(data, opt); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, diag := range diags.Items() {
+		got = append(got, diag.Cell+" "+diag.Message)
+	}
+	wantDiags := []string{
+		"E5 oneof Target has multiple fields set: Item,Gold",
+		"C6 oneof Target kind is Gold but Item is set",
+		"B7 oneof Target has no field Bad",
+		"G8 oneof Sub.Value has multiple fields set: IntValue,StrValue",
+	}
+	if !reflect.DeepEqual(got, wantDiags) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, wantDiags)
+	}
+
+	// object格式
+	f2 := newTestSheetFile(t, "OneOfObj",
+		[]interface{}{"key", "value"},
+		[]interface{}{"CfgId", "1"},
+		[]interface{}{"Target", "None"},
+		[]interface{}{"Gold", "5"},
+	)
+	defer func() { _ = f2.Close() }()
+	diags = NewDiagnostics()
+	objOpt := &SheetOption{SheetName: "OneOfObj", MessageName: "OneOfCfg", MgrType: "object"}
+	data, err = ConvertSourceSheet(&ExportOption{}, NewExcelSource(f2), objOpt, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := toJson(data); got != `{"CfgId":1,"None":{}}` {
+		t.Fatalf("object got:\n%v", got)
+	}
+	if _, err = marshalToProtoBinary(data, objOpt); err != nil {
+		t.Fatal(err)
+	}
+	if len(diags.Items()) != 1 || diags.Items()[0].Message != "oneof Target kind is None but Gold is set" {
+		t.Fatalf("unexpected diags: %v", diags.Items())
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"github.com/jhump/protoreflect/desc/protoparse"
 	"google.golang.org/protobuf/types/descriptorpb"
 	"strings"
+	"sync"
 )
 
 var (
-	_protoDesc []*desc.FileDescriptor
+	_protoDesc     []*desc.FileDescriptor
+	_protoDescLock sync.RWMutex
 )
 
 // 解析proto文件
@@ -23,7 +25,9 @@ func ParseProtoFile(importPaths []string, filenames ...string) error {
 	if err != nil {
 		return err
 	}
+	_protoDescLock.Lock()
 	_protoDesc = append(_protoDesc, protoDesc...)
+	_protoDescLock.Unlock()
 	for _, fd := range protoDesc {
 		fmt.Println(fmt.Sprintf("ParseProtoFile Name:%v FullyQualifiedName:%v Package:%v",
 			fd.GetName(), fd.GetFullyQualifiedName(), fd.GetPackage()))
@@ -31,8 +35,47 @@ func ParseProtoFile(importPaths []string, filenames ...string) error {
 	return nil
 }
 
+// 清空已经解析的proto文件,用于proto文件修改后重新解析
+func ResetProtoFile() {
+	_protoDescLock.Lock()
+	_protoDesc = nil
+	_protoDescLock.Unlock()
+	_fieldRuleCache.Clear()
+	_messageRuleCache.Clear()
+	_messageHasRuleCache.Clear()
+	_optionTypes.Clear()
+	_messageHasOneOfCache.Clear()
+}
+
+// 遍历已经解析的proto文件和它们依赖的proto文件,每个文件只遍历一次
+// 解析器内置的well-known types(google/protobuf/*.proto)不遍历
+func rangeProtoFiles(fn func(fd *desc.FileDescriptor)) {
+	_protoDescLock.RLock()
+	defer _protoDescLock.RUnlock()
+	visited := make(map[*desc.FileDescriptor]struct{})
+	var visit func(fd *desc.FileDescriptor)
+	visit = func(fd *desc.FileDescriptor) {
+		if _, ok := visited[fd]; ok {
+			return
+		}
+		visited[fd] = struct{}{}
+		if strings.HasPrefix(fd.GetName(), "google/protobuf/") {
+			return
+		}
+		for _, dep := range fd.GetDependencies() {
+			visit(dep)
+		}
+		fn(fd)
+	}
+	for _, fd := range _protoDesc {
+		visit(fd)
+	}
+}
+
 // 获取message的结构描述
 func FindMessageDescriptor(messageName string) *desc.MessageDescriptor {
+	_protoDescLock.RLock()
+	defer _protoDescLock.RUnlock()
 	for _, fd := range _protoDesc {
 		msgName := messageName
 		if fd.GetPackage() != "" {
@@ -47,34 +90,54 @@ func FindMessageDescriptor(messageName string) *desc.MessageDescriptor {
 }
 
 // 获取message的字段的结构描述
+// 支持任意层级的字段展开,如Child.Id,Base.Pos.X,中间的字段必须是非repeated的message或者map
+// map字段的下一层是map的key,返回map的value字段,如Progress.IntEventFields.Level
+// repeated字段可以用下标指定元素,如Rewards[0].CfgId,NextQuests[1](返回repeated字段)
 func FindFieldDescriptor(msgDesc *desc.MessageDescriptor, fieldName string) *desc.FieldDescriptor {
 	// 支持字段展开
-	if strings.Index(fieldName, ".") > 0 {
-		names := strings.Split(fieldName, ".") // child.fieldName
-		if len(names) != 2 {
+	if strings.Index(fieldName, ".") > 0 || strings.Contains(fieldName, "[") {
+		segments, ok := parseColumnPath(fieldName) // child.fieldName
+		if !ok {
 			color.Red("fieldName error1: %v %v", msgDesc.GetName(), fieldName)
 			return nil
 		}
-		childName := names[0]
-		childFieldName := names[1]
-		childDesc := msgDesc.FindFieldByName(childName)
-		if childDesc == nil {
-			color.Red("fieldName error2: %v %v", msgDesc.GetName(), fieldName)
-			return nil
-		}
-		// child必须是个message
-		if childDesc.GetType() != descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
-			color.Red("fieldName error3: %v %v", msgDesc.GetName(), fieldName)
-			return nil
-		}
-		childMessageDesc := childDesc.GetMessageType()
-		if childMessageDesc == nil {
-			color.Red("fieldName error4: %v %v", msgDesc.GetName(), fieldName)
-			return nil
-		}
-		fieldDesc := childMessageDesc.FindFieldByName(childFieldName)
-		if fieldDesc == nil {
-			fieldDesc = childMessageDesc.FindFieldByJSONName(childFieldName)
+		var fieldDesc *desc.FieldDescriptor
+		isElem := false // 上一层是带下标的repeated字段的元素
+		childMessageDesc := msgDesc
+		for i, segment := range segments {
+			if fieldDesc != nil && fieldDesc.IsMap() && segment.Index < 0 {
+				fieldDesc = fieldDesc.GetMapValueType()
+				isElem = false
+				continue
+			}
+			if fieldDesc != nil {
+				// child必须是个message
+				if fieldDesc.GetType() != descriptorpb.FieldDescriptorProto_TYPE_MESSAGE || (fieldDesc.IsRepeated() && !isElem) {
+					color.Red("fieldName error3: %v %v", msgDesc.GetName(), fieldName)
+					return nil
+				}
+				childMessageDesc = fieldDesc.GetMessageType()
+				if childMessageDesc == nil {
+					color.Red("fieldName error4: %v %v", msgDesc.GetName(), fieldName)
+					return nil
+				}
+			}
+			fieldDesc = childMessageDesc.FindFieldByName(segment.Name)
+			if fieldDesc == nil {
+				fieldDesc = childMessageDesc.FindFieldByJSONName(segment.Name)
+			}
+			if fieldDesc == nil {
+				if i < len(segments)-1 {
+					color.Red("fieldName error2: %v %v", msgDesc.GetName(), fieldName)
+				}
+				return nil
+			}
+			isElem = segment.Index >= 0
+			// 只有repeated字段可以使用下标
+			if isElem && (!fieldDesc.IsRepeated() || fieldDesc.IsMap()) {
+				color.Red("fieldName error5: %v %v", msgDesc.GetName(), fieldName)
+				return nil
+			}
 		}
 		return fieldDesc
 	}
@@ -0,0 +1,300 @@
+package tool
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// excelexporter.proto中定义的字段选项的名字
+const (
+	fieldOptionMin      = "excel.min"
+	fieldOptionMax      = "excel.max"
+	fieldOptionRegex    = "excel.regex"
+	fieldOptionRequired = "excel.required"
+	fieldOptionUnique   = "excel.unique"
+	fieldOptionIn       = "excel.in"
+	fieldOptionRef      = "excel.ref"
+)
+
+var fieldOptionNames = []string{
+	fieldOptionMin, fieldOptionMax, fieldOptionRegex, fieldOptionRequired, fieldOptionUnique, fieldOptionIn, fieldOptionRef,
+}
+
+// excelexporter.proto中定义的message选项的名字
+const (
+	messageOptionUniqueKey = "excel.unique_key"
+)
+
+var messageOptionNames = []string{
+	messageOptionUniqueKey,
+}
+
+// proto字段选项中声明的校验规则
+type fieldRule struct {
+	Constraint *ColumnConstraint
+	Ref        string
+}
+
+// proto message选项中声明的校验规则
+type messageRule struct {
+	UniqueKeys [][]*desc.FieldDescriptor // 每一组字段的组合不能重复
+}
+
+var (
+	_fieldRuleCache      sync.Map // *desc.FieldDescriptor -> *fieldRule
+	_messageRuleCache    sync.Map // *desc.MessageDescriptor -> *messageRule
+	_messageHasRuleCache sync.Map // *desc.MessageDescriptor -> bool
+	_optionTypes         sync.Map // *desc.FileDescriptor -> *protoregistry.Types
+)
+
+// 获取字段选项中的校验规则,没有配置时返回nil
+func getFieldRule(fieldDesc *desc.FieldDescriptor) *fieldRule {
+	if v, ok := _fieldRuleCache.Load(fieldDesc); ok {
+		return v.(*fieldRule)
+	}
+	rule := parseFieldRule(fieldDesc)
+	_fieldRuleCache.Store(fieldDesc, rule)
+	return rule
+}
+
+// 获取message选项中的校验规则,没有配置时返回nil
+func getMessageRule(msgDesc *desc.MessageDescriptor) *messageRule {
+	if v, ok := _messageRuleCache.Load(msgDesc); ok {
+		return v.(*messageRule)
+	}
+	rule := parseMessageRule(msgDesc)
+	_messageRuleCache.Store(msgDesc, rule)
+	return rule
+}
+
+// 获取proto文件import的excelexporter.proto中定义的字段选项和message选项,没有import时返回nil
+func getOptionTypes(fd *desc.FileDescriptor) *protoregistry.Types {
+	if v, ok := _optionTypes.Load(fd); ok {
+		return v.(*protoregistry.Types)
+	}
+	var types *protoregistry.Types
+	visited := make(map[*desc.FileDescriptor]struct{})
+	var findFn func(fd *desc.FileDescriptor)
+	findFn = func(fd *desc.FileDescriptor) {
+		if _, ok := visited[fd]; ok {
+			return
+		}
+		visited[fd] = struct{}{}
+		registerFn := func(names []string, owner string) {
+			for _, name := range names {
+				extDesc, ok := fd.FindSymbol(name).(*desc.FieldDescriptor)
+				if !ok || !extDesc.IsExtension() || extDesc.GetOwner().GetFullyQualifiedName() != owner {
+					continue
+				}
+				if types == nil {
+					types = &protoregistry.Types{}
+				}
+				if err := types.RegisterExtension(dynamicpb.NewExtensionType(extDesc.UnwrapField())); err != nil {
+					color.Red("register option err:%v file:%v", err, fd.GetName())
+				}
+			}
+		}
+		registerFn(fieldOptionNames, "google.protobuf.FieldOptions")
+		registerFn(messageOptionNames, "google.protobuf.MessageOptions")
+		for _, dep := range fd.GetDependencies() {
+			findFn(dep)
+		}
+	}
+	findFn(fd)
+	_optionTypes.Store(fd, types)
+	return types
+}
+
+// 自定义选项没有对应的go类型,解析proto时保存在options的unknown fields中
+// 用excelexporter.proto中定义的扩展字段重新解析到resolvedOptions
+func resolveOptions(types *protoregistry.Types, options, resolvedOptions proto.Message) error {
+	optionData, err := proto.Marshal(options)
+	if err != nil {
+		return err
+	}
+	return proto.UnmarshalOptions{Resolver: types}.Unmarshal(optionData, resolvedOptions)
+}
+
+// 解析字段选项中的校验规则
+func parseFieldRule(fieldDesc *desc.FieldDescriptor) *fieldRule {
+	options := fieldDesc.GetFieldOptions()
+	if options == nil || len(options.ProtoReflect().GetUnknown()) == 0 {
+		return nil
+	}
+	types := getOptionTypes(fieldDesc.GetFile())
+	if types == nil {
+		return nil
+	}
+	resolvedOptions := &descriptorpb.FieldOptions{}
+	err := resolveOptions(types, options, resolvedOptions)
+	if err != nil {
+		color.Red("parse field options err:%v field:%v", err, fieldDesc.GetFullyQualifiedName())
+		return nil
+	}
+	getOption := func(name string) (protoreflect.Value, bool) {
+		xt, err := types.FindExtensionByName(protoreflect.FullName(name))
+		if err != nil || !proto.HasExtension(resolvedOptions, xt) {
+			return protoreflect.Value{}, false
+		}
+		return resolvedOptions.ProtoReflect().Get(xt.TypeDescriptor()), true
+	}
+	constraint := &ColumnConstraint{}
+	rule := &fieldRule{}
+	hasConstraint := false
+	if v, ok := getOption(fieldOptionMin); ok {
+		f64 := v.Float()
+		constraint.Min = &f64
+		hasConstraint = true
+	}
+	if v, ok := getOption(fieldOptionMax); ok {
+		f64 := v.Float()
+		constraint.Max = &f64
+		hasConstraint = true
+	}
+	if v, ok := getOption(fieldOptionRegex); ok {
+		if _, err = constraint.parseArg("regex", v.String()); err != nil {
+			color.Red("field:%v err:%v", fieldDesc.GetFullyQualifiedName(), err)
+		} else {
+			hasConstraint = true
+		}
+	}
+	if v, ok := getOption(fieldOptionRequired); ok && v.Bool() {
+		constraint.Required = true
+		hasConstraint = true
+	}
+	if v, ok := getOption(fieldOptionUnique); ok && v.Bool() {
+		constraint.Unique = true
+		hasConstraint = true
+	}
+	if v, ok := getOption(fieldOptionIn); ok {
+		list := v.List()
+		for i := 0; i < list.Len(); i++ {
+			constraint.In = append(constraint.In, list.Get(i).String())
+		}
+		hasConstraint = hasConstraint || len(constraint.In) > 0
+	}
+	if v, ok := getOption(fieldOptionRef); ok {
+		rule.Ref = v.String()
+	}
+	if hasConstraint {
+		rule.Constraint = constraint
+	}
+	if rule.Constraint == nil && rule.Ref == "" {
+		return nil
+	}
+	return rule
+}
+
+// 解析message选项,unique_key中的字段名可以是proto字段名或者json名
+func parseMessageRule(msgDesc *desc.MessageDescriptor) *messageRule {
+	options := msgDesc.GetMessageOptions()
+	if options == nil || len(options.ProtoReflect().GetUnknown()) == 0 {
+		return nil
+	}
+	types := getOptionTypes(msgDesc.GetFile())
+	if types == nil {
+		return nil
+	}
+	resolvedOptions := &descriptorpb.MessageOptions{}
+	if err := resolveOptions(types, options, resolvedOptions); err != nil {
+		color.Red("parse message options err:%v message:%v", err, msgDesc.GetFullyQualifiedName())
+		return nil
+	}
+	xt, err := types.FindExtensionByName(messageOptionUniqueKey)
+	if err != nil || !proto.HasExtension(resolvedOptions, xt) {
+		return nil
+	}
+	rule := &messageRule{}
+	list := resolvedOptions.ProtoReflect().Get(xt.TypeDescriptor()).List()
+	for i := 0; i < list.Len(); i++ {
+		var keyFields []*desc.FieldDescriptor
+		for _, name := range strings.Split(list.Get(i).String(), ",") {
+			name = strings.TrimSpace(name)
+			fieldDesc := msgDesc.FindFieldByName(name)
+			if fieldDesc == nil {
+				fieldDesc = msgDesc.FindFieldByJSONName(name)
+			}
+			if fieldDesc == nil {
+				color.Red("message:%v unique_key field %v not found", msgDesc.GetFullyQualifiedName(), name)
+				keyFields = nil
+				break
+			}
+			keyFields = append(keyFields, fieldDesc)
+		}
+		if len(keyFields) > 0 {
+			rule.UniqueKeys = append(rule.UniqueKeys, keyFields)
+		}
+	}
+	if len(rule.UniqueKeys) == 0 {
+		return nil
+	}
+	return rule
+}
+
+// message或者嵌套的子message中是否有字段配置了校验规则
+func messageHasFieldRule(msgDesc *desc.MessageDescriptor) bool {
+	if v, ok := _messageHasRuleCache.Load(msgDesc); ok {
+		return v.(bool)
+	}
+	has := checkMessageHasFieldRule(msgDesc, make(map[*desc.MessageDescriptor]struct{}))
+	_messageHasRuleCache.Store(msgDesc, has)
+	return has
+}
+
+func checkMessageHasFieldRule(msgDesc *desc.MessageDescriptor, visited map[*desc.MessageDescriptor]struct{}) bool {
+	if _, ok := visited[msgDesc]; ok {
+		return false
+	}
+	visited[msgDesc] = struct{}{}
+	for _, fieldDesc := range msgDesc.GetFields() {
+		if getFieldRule(fieldDesc) != nil {
+			return true
+		}
+		if subMsgDesc := getValueMessageType(fieldDesc); subMsgDesc != nil && checkMessageHasFieldRule(subMsgDesc, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// 字段值对应的message类型,map字段返回map value的message类型,不是message时返回nil
+func getValueMessageType(fieldDesc *desc.FieldDescriptor) *desc.MessageDescriptor {
+	if fieldDesc.IsMap() {
+		return fieldDesc.GetMapValueType().GetMessageType()
+	}
+	return fieldDesc.GetMessageType()
+}
+
+// 合并列名中的校验规则和proto字段选项中的校验规则,列名中的优先
+func mergeConstraint(columnConstraint, fieldConstraint *ColumnConstraint) *ColumnConstraint {
+	if columnConstraint == nil {
+		return fieldConstraint
+	}
+	if fieldConstraint == nil {
+		return columnConstraint
+	}
+	merged := *fieldConstraint
+	if columnConstraint.Min != nil {
+		merged.Min = columnConstraint.Min
+	}
+	if columnConstraint.Max != nil {
+		merged.Max = columnConstraint.Max
+	}
+	if columnConstraint.Regex != "" {
+		merged.Regex = columnConstraint.Regex
+	}
+	if len(columnConstraint.In) > 0 {
+		merged.In = columnConstraint.In
+	}
+	merged.Required = merged.Required || columnConstraint.Required
+	merged.Unique = merged.Unique || columnConstraint.Unique
+	return &merged
+}
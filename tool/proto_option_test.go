@@ -0,0 +1,291 @@
+package tool
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const optionTestProto = `syntax = "proto3";
+package optiontest;
+import "excelexporter.proto";
+
+message OptItem {
+  int32 CfgId = 1 [(excel.unique)=true];
+  string Name = 2 [(excel.required)=true, (excel.regex)="^[a-z]+$"];
+  int32 Type = 3 [(excel.in)="1", (excel.in)="2"];
+}
+
+message OptReward {
+  int32 CfgId = 1 [(excel.ref)="OptItem", (excel.min)=1];
+  int32 Num = 2 [(excel.min)=1, (excel.max)=100];
+}
+
+message OptQuest {
+  int32 CfgId = 1;
+  repeated OptReward Rewards = 2;
+  int32 PreItem = 3 [(excel.ref)="OptItem"];
+}
+
+message OptStage {
+  option (excel.unique_key) = "ActivityId, Stage";
+  option (excel.unique_key) = "Name";
+  option (excel.unique_key) = "Unknown";
+  int32 CfgId = 1;
+  int32 ActivityId = 2;
+  int32 Stage = 3;
+  string Name = 4;
+}
+`
+
+func TestGetFieldRule(t *testing.T) {
+	initTempProtoForTest(t, "option_test.proto", optionTestProto)
+	msgDesc := FindMessageDescriptor("OptReward")
+	if msgDesc == nil {
+		t.Fatal("message not found")
+	}
+	rule := getFieldRule(msgDesc.FindFieldByName("CfgId"))
+	if rule == nil || rule.Ref != "OptItem" || rule.Constraint == nil || *rule.Constraint.Min != 1 || rule.Constraint.Max != nil {
+		t.Fatalf("unexpected rule: %+v", rule)
+	}
+	rule = getFieldRule(FindMessageDescriptor("OptItem").FindFieldByName("Type"))
+	if rule == nil || !reflect.DeepEqual(rule.Constraint.In, []string{"1", "2"}) {
+		t.Fatalf("unexpected rule: %+v", rule)
+	}
+	if rule = getFieldRule(FindMessageDescriptor("OptQuest").FindFieldByName("CfgId")); rule != nil {
+		t.Fatalf("unexpected rule: %+v", rule)
+	}
+	if !messageHasFieldRule(FindMessageDescriptor("OptQuest")) {
+		t.Fatal("OptQuest has nested rules")
+	}
+}
+
+// 其他proto中使用相同编号的扩展字段,不是excelexporter.proto中定义的选项
+func TestGetFieldRuleOtherExtension(t *testing.T) {
+	initTempProtoForTest(t, "other_option_test.proto", `syntax = "proto3";
+package otheroption;
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.FieldOptions {
+  double min = 51001;
+  string ref = 51007;
+}
+
+message OtherItem {
+  int32 CfgId = 1 [(otheroption.min)=1, (otheroption.ref)="OptItem"];
+}
+`)
+	if rule := getFieldRule(FindMessageDescriptor("OtherItem").FindFieldByName("CfgId")); rule != nil {
+		t.Fatalf("unexpected rule: %+v", rule)
+	}
+}
+
+func TestProtoOptionConstraint(t *testing.T) {
+	initTempProtoForTest(t, "option_test.proto", optionTestProto)
+	diagStrings := func(diags *Diagnostics) []string {
+		var result []string
+		for _, diag := range diags.Items() {
+			result = append(result, diag.Cell+" "+diag.Message)
+		}
+		return result
+	}
+	f := newTestSheetFile(t, "OptItem",
+		[]interface{}{"CfgId", "Name", "Type"},
+		[]interface{}{"1", "abc", "1"},
+		[]interface{}{"2", "", "3"},
+		[]interface{}{"1", "Abc", "2"},
+	)
+	defer func() { _ = f.Close() }()
+	diags := NewDiagnostics()
+	itemData, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), &SheetOption{SheetName: "OptItem", MessageName: "OptItem", MgrType: "map"}, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"B3 required value is empty",
+		"C3 value 3 not in [1,2]",
+		"A4 duplicate value 1, first at A2",
+		"B4 value \"Abc\" not match regex ^[a-z]+$",
+		"A4 duplicate key 1, previous at A2",
+	}
+	if got := diagStrings(diags); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+
+	// 缺少required字段的列
+	f2 := newTestSheetFile(t, "OptItem2",
+		[]interface{}{"CfgId", "Type"},
+		[]interface{}{"3", "1"},
+	)
+	defer func() { _ = f2.Close() }()
+	diags = NewDiagnostics()
+	if _, err = ConvertSourceSheet(&ExportOption{}, NewExcelSource(f2), &SheetOption{SheetName: "OptItem2", MessageName: "OptItem", MgrType: "map"}, diags); err != nil {
+		t.Fatal(err)
+	}
+	if got := diagStrings(diags); !reflect.DeepEqual(got, []string{" required field Name has no column"}) {
+		t.Fatalf("unexpected diags: %v", got)
+	}
+
+	// 嵌套的子message按字段选项检查
+	f3 := newTestSheetFile(t, "OptQuest",
+		[]interface{}{"CfgId", "Rewards", "PreItem"},
+		[]interface{}{"1", "0_5;2_200", "1"},
+		[]interface{}{"2", "3_1", "5"},
+	)
+	defer func() { _ = f3.Close() }()
+	diags = NewDiagnostics()
+	questOpt := &SheetOption{SheetName: "OptQuest", MessageName: "OptQuest", MgrType: "map"}
+	questData, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f3), questOpt, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"B2 field:Rewards.CfgId value 0 less than min 1",
+		"B2 field:Rewards.Num value 200 greater than max 100",
+	}
+	if got := diagStrings(diags); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+
+	// (excel.ref)可以填message名
+	exportInfoMap := map[string]*ExportInfo{
+		"item.OptItem":   {MgrData: itemData, SheetOption: &SheetOption{SheetName: "OptItem", MessageName: "OptItem", MgrType: "map", MapKeyName: "CfgId"}},
+		"quest.OptQuest": {MgrData: questData, SheetOption: &SheetOption{SheetName: "OptQuest", MessageName: "OptQuest", MgrType: "map", MapKeyName: "CfgId"}},
+	}
+	orderNames := []string{"item.OptItem", "quest.OptQuest"}
+	refCheckMap := map[string]*ExportInfo{
+		"Items":    exportInfoMap["item.OptItem"],
+		"OptQuest": exportInfoMap["quest.OptQuest"],
+	}
+	diags = NewDiagnostics()
+	checkRefs(exportInfoMap, orderNames, refCheckMap, diags)
+	var got []string
+	for _, diag := range diags.Items() {
+		got = append(got, diag.Column+" "+diag.Message)
+	}
+	want = []string{
+		"Rewards.CfgId ref ERROR ref:OptItem checkId:0 row:1",
+		"Rewards.CfgId ref ERROR ref:OptItem checkId:3 row:2",
+		"PreItem ref ERROR ref:OptItem checkId:5 row:2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestGetMessageRule(t *testing.T) {
+	initTempProtoForTest(t, "option_test.proto", optionTestProto)
+	rule := getMessageRule(FindMessageDescriptor("OptStage"))
+	if rule == nil {
+		t.Fatal("message rule not found")
+	}
+	var got []string
+	for _, keyFields := range rule.UniqueKeys {
+		var names []string
+		for _, fieldDesc := range keyFields {
+			names = append(names, fieldDesc.GetName())
+		}
+		got = append(got, strings.Join(names, ","))
+	}
+	// 字段不存在的unique_key忽略
+	if !reflect.DeepEqual(got, []string{"ActivityId,Stage", "Name"}) {
+		t.Fatalf("unexpected unique keys: %v", got)
+	}
+	if rule = getMessageRule(FindMessageDescriptor("OptItem")); rule != nil {
+		t.Fatalf("unexpected rule: %+v", rule)
+	}
+}
+
+func TestProtoOptionUniqueKey(t *testing.T) {
+	initTempProtoForTest(t, "option_test.proto", optionTestProto)
+	f := newTestSheetFile(t, "OptStage",
+		[]interface{}{"CfgId", "ActivityId", "Stage", "Name"},
+		[]interface{}{"1", "1", "1", "a"},
+		[]interface{}{"2", "1", "2", "b"},
+		[]interface{}{"3", "1", "1", "c"},
+		[]interface{}{"4", "2", "1", "b"},
+	)
+	defer func() { _ = f.Close() }()
+	for _, mgrType := range []string{"map", "slice"} {
+		diags := NewDiagnostics()
+		if _, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), &SheetOption{SheetName: "OptStage", MessageName: "OptStage", MgrType: mgrType}, diags); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, diag := range diags.Items() {
+			got = append(got, diag.Cell+" "+diag.Message)
+		}
+		want := []string{
+			"B4 duplicate unique_key ActivityId,Stage value 1,1, first at B2",
+			"D5 duplicate unique_key Name value b, first at D3",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("mgrType:%v got:\n%v\nwant:\n%v", mgrType, got, want)
+		}
+	}
+
+	// cfg.proto中ItemCfg的选项
+	initProtoForTest(t)
+	f2 := newTestSheetFile(t, "ItemCfg",
+		[]interface{}{"CfgId", "Name"},
+		[]interface{}{"1", "item1"},
+		[]interface{}{"0", "item1"},
+	)
+	defer func() { _ = f2.Close() }()
+	diags := NewDiagnostics()
+	if _, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f2), &SheetOption{SheetName: "ItemCfg", MessageName: "ItemCfg", MgrType: "map"}, diags); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, diag := range diags.Items() {
+		got = append(got, diag.Cell+" "+diag.Message)
+	}
+	want := []string{
+		"A3 value 0 less than min 1",
+		"B3 duplicate unique_key Name value item1, first at B2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestProtoOptionObject(t *testing.T) {
+	initTempProtoForTest(t, "option_test.proto", optionTestProto)
+	f := newTestSheetFile(t, "OptItemObj",
+		[]interface{}{"Key", "Value"},
+		[]interface{}{"CfgId", "1"},
+		[]interface{}{"Type", "3"},
+	)
+	defer func() { _ = f.Close() }()
+	diags := NewDiagnostics()
+	if _, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), &SheetOption{SheetName: "OptItemObj", MessageName: "OptItem", MgrType: "object"}, diags); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, diag := range diags.Items() {
+		got = append(got, diag.Cell+" "+diag.Message)
+	}
+	// 字段选项按行检查,required的字段必须有对应的行
+	want := []string{
+		"B3 value 3 not in [1,2]",
+		" required field Name has no row",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+
+	// 有对应的行但是值为空
+	f2 := newTestSheetFile(t, "OptItemObj",
+		[]interface{}{"Key", "Value"},
+		[]interface{}{"CfgId", "1"},
+		[]interface{}{"Name"},
+	)
+	defer func() { _ = f2.Close() }()
+	diags = NewDiagnostics()
+	if _, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f2), &SheetOption{SheetName: "OptItemObj", MessageName: "OptItem", MgrType: "object"}, diags); err != nil {
+		t.Fatal(err)
+	}
+	if items := diags.Items(); len(items) != 1 || items[0].Cell != "B3" || items[0].Message != "required value is empty" {
+		t.Fatalf("unexpected diags: %v", items)
+	}
+}
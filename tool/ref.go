@@ -0,0 +1,587 @@
+package tool
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// 关联检查的目标
+type refTarget struct {
+	values    map[string]struct{} // 目标配置表中所有可以被关联的值,统一转换成字符串
+	fieldName string              // 关联的值是message时,取这个字段的值做检查
+}
+
+func (t *refTarget) contains(value any) (string, bool) {
+	s, ok := refValueString(value)
+	if !ok {
+		return s, true // 不支持的类型不检查
+	}
+	_, ok = t.values[s]
+	return s, ok
+}
+
+// ref功能,检查数据关联
+//
+//	#Ref=Item        检查值是否是配置表Item(MgrType=map group)的key
+//	#Ref=Item.Name   检查值是否在配置表Item的Name列中存在,Name可以是多层的字段,如Item.Base.Name
+//
+// 字段是repeated时检查每个元素,map字段检查map的key,message检查和关联字段同名的子字段
+// proto字段选项(excel.ref)声明的关联检查也在这里处理,包括嵌套的子message中的字段
+func checkRefs(exportInfoMap map[string]*ExportInfo, orderNames []string, refCheckMap map[string]*ExportInfo, diags *Diagnostics) {
+	targets := make(map[string]*refTarget)
+	targetErrs := make(map[string]error)
+	getTarget := func(ref string) (*refTarget, error) {
+		if target, ok := targets[ref]; ok {
+			return target, nil
+		}
+		if err, ok := targetErrs[ref]; ok {
+			return nil, err
+		}
+		target, err := newRefTarget(ref, refCheckMap)
+		if err != nil {
+			targetErrs[ref] = err
+			return nil, err
+		}
+		targets[ref] = target
+		return target, nil
+	}
+	for _, name := range orderNames {
+		exportInfo := exportInfoMap[name]
+		sheetOption := exportInfo.SheetOption
+		if sheetOption.MgrType == "object" {
+			continue
+		}
+		msgDesc := FindMessageDescriptor(sheetOption.MessageName)
+		if msgDesc == nil {
+			continue
+		}
+		refErrorFn := func(column, format string, args ...any) {
+			diags.Add(&Diagnostic{
+				Severity:  SeverityError,
+				ExcelName: sheetOption.ExcelName,
+				SheetName: sheetOption.SheetName,
+				Column:    column,
+				Message:   fmt.Sprintf(format, args...),
+			})
+		}
+		// 关联的值不存在,定位到数据所在的单元格
+		refRowErrorFn := func(column string, rowKey any, groupIndex int, ref, checkId string) {
+			source := exportInfo.getRowSource(rowKey, groupIndex)
+			if source == nil {
+				refErrorFn(column, "ref ERROR ref:%v checkId:%v row:%v", ref, checkId, rowKey)
+				return
+			}
+			diags.Add(&Diagnostic{
+				Severity:  SeverityError,
+				ExcelName: source.SheetOption.ExcelName,
+				SheetName: source.SheetOption.SheetName,
+				Cell:      source.cellName(column),
+				Column:    column,
+				Message:   fmt.Sprintf("ref ERROR ref:%v checkId:%v row:%v", ref, checkId, rowKey),
+			})
+		}
+		checkedColumns := make(map[string]struct{}) // #Merge的多列是同一个字段,只检查一次
+		for _, columnOption := range sheetOption.ColumnOpts {
+			if columnOption.Ref == "" {
+				continue
+			}
+			// 带下标的列(如Rewards[0].CfgId)检查repeated字段的所有元素,多个下标的列只检查一次
+			columnName := stripColumnIndex(columnOption.Name)
+			if _, ok := checkedColumns[columnName]; ok {
+				continue
+			}
+			checkedColumns[columnName] = struct{}{}
+			target, err := getTarget(columnOption.Ref)
+			if err != nil {
+				refErrorFn(columnName, "%v", err)
+				continue
+			}
+			path := strings.Split(columnName, ".")
+			rangeRefRows(exportInfo.MgrData, func(rowKey any, groupIndex int, row map[string]any) {
+				rangeRefFieldValue(msgDesc, row, path, func(fieldDesc *desc.FieldDescriptor, value any) {
+					rangeRefValue(fieldDesc, value, target.fieldName, func(checkValue any) {
+						if checkId, ok := target.contains(checkValue); !ok {
+							refRowErrorFn(columnName, rowKey, groupIndex, columnOption.Ref, checkId)
+						}
+					})
+				})
+			})
+		}
+		// proto字段选项中声明的关联检查,列名中配置了#Ref的字段以列名中的为准
+		if !messageHasFieldRule(msgDesc) {
+			continue
+		}
+		reportedFields := make(map[string]struct{})
+		rangeRefRows(exportInfo.MgrData, func(rowKey any, groupIndex int, row map[string]any) {
+			rangeRuleRefs(msgDesc, "", row, func(fieldPath string, fieldDesc *desc.FieldDescriptor, ref string, value any) {
+				if _, ok := checkedColumns[fieldPath]; ok {
+					return
+				}
+				target, err := getTarget(ref)
+				if err != nil {
+					if _, ok := reportedFields[fieldPath]; !ok {
+						reportedFields[fieldPath] = struct{}{}
+						refErrorFn(fieldPath, "%v", err)
+					}
+					return
+				}
+				rangeRefValue(fieldDesc, value, target.fieldName, func(checkValue any) {
+					if checkId, ok := target.contains(checkValue); !ok {
+						refRowErrorFn(fieldPath, rowKey, groupIndex, ref, checkId)
+					}
+				})
+			})
+		})
+	}
+}
+
+// 遍历message中配置了(excel.ref)的字段,包括嵌套的子message
+func rangeRuleRefs(msgDesc *desc.MessageDescriptor, msgPath string, m map[string]any, fn func(fieldPath string, fieldDesc *desc.FieldDescriptor, ref string, value any)) {
+	for _, fieldDesc := range msgDesc.GetFields() {
+		rule := getFieldRule(fieldDesc)
+		hasRef := rule != nil && rule.Ref != ""
+		subMsgDesc := getValueMessageType(fieldDesc)
+		hasSubRule := subMsgDesc != nil && messageHasFieldRule(subMsgDesc)
+		if !hasRef && !hasSubRule {
+			continue
+		}
+		value, ok := m[fieldDesc.GetJSONName()]
+		if !ok {
+			value = m[fieldDesc.GetName()]
+		}
+		if value == nil {
+			continue
+		}
+		fieldPath := fieldDesc.GetName()
+		if msgPath != "" {
+			fieldPath = msgPath + "." + fieldPath
+		}
+		if hasRef {
+			fn(fieldPath, fieldDesc, rule.Ref, value)
+		}
+		if !hasSubRule {
+			continue
+		}
+		var elems []any
+		switch {
+		case fieldDesc.IsMap():
+			rv := reflect.ValueOf(value)
+			if rv.Kind() == reflect.Map {
+				keys := rv.MapKeys()
+				sort.Slice(keys, func(i, j int) bool {
+					return lessMapKey(keys[i], keys[j])
+				})
+				for _, key := range keys {
+					elems = append(elems, rv.MapIndex(key).Interface())
+				}
+			}
+		case fieldDesc.IsRepeated():
+			elems, _ = value.([]any)
+		default:
+			elems = []any{value}
+		}
+		for _, elem := range elems {
+			if subMsg, ok := elem.(map[string]any); ok {
+				rangeRuleRefs(subMsgDesc, fieldPath, subMsg, fn)
+			}
+		}
+	}
+}
+
+// 一条数据的来源
+type refRowSource struct {
+	SheetOption *SheetOption
+	RowIndex    int
+}
+
+// 记录sheet中每条数据的来源,offset是合并slice时的下标偏移
+// map类型合并时后面的sheet覆盖前面的,group类型合并时追加到后面,和mergeMgrData一致
+func (info *ExportInfo) addRowSources(sheetOption *SheetOption, offset int) {
+	if info.rowSources == nil {
+		info.rowSources = make(map[string][]refRowSource)
+	}
+	for key, rowIndexes := range sheetOption.RowIndexes {
+		if sheetOption.MgrType == "slice" {
+			i, err := strconv.Atoi(key)
+			if err != nil {
+				continue
+			}
+			key = strconv.Itoa(i + offset)
+		}
+		sources := make([]refRowSource, 0, len(rowIndexes))
+		for _, rowIndex := range rowIndexes {
+			sources = append(sources, refRowSource{SheetOption: sheetOption, RowIndex: rowIndex})
+		}
+		if sheetOption.MgrType == "group" {
+			info.rowSources[key] = append(info.rowSources[key], sources...)
+		} else {
+			info.rowSources[key] = sources
+		}
+	}
+}
+
+func (info *ExportInfo) getRowSource(rowKey any, groupIndex int) *refRowSource {
+	key, _ := refValueString(rowKey)
+	sources := info.rowSources[key]
+	if groupIndex < 0 || groupIndex >= len(sources) {
+		return nil
+	}
+	return &sources[groupIndex]
+}
+
+// 字段所在的单元格,找不到字段对应的列时,使用第一列(一般是key列)
+func (s *refRowSource) cellName(fieldPath string) string {
+	columnOpts := s.SheetOption.ColumnOpts
+	if len(columnOpts) == 0 {
+		return ""
+	}
+	var column *ColumnOption
+	firstName, _, _ := strings.Cut(fieldPath, ".")
+	for _, columnOpt := range columnOpts {
+		name := stripColumnIndex(columnOpt.Name)
+		if name == fieldPath {
+			column = columnOpt
+			break
+		}
+		// 子字段在json格式或者#Field格式的列中
+		if column == nil && name == firstName {
+			column = columnOpt
+		}
+	}
+	if column == nil {
+		column = columnOpts[0]
+	}
+	cellCtx := &CellContext{
+		RowIndex: s.RowIndex,
+		Column:   column,
+		Vertical: s.SheetOption.Layout == layoutVertical,
+	}
+	return cellCtx.CellName()
+}
+
+// 根据配置表名或者message名查找关联的配置表
+func findRefInfo(name string, refCheckMap map[string]*ExportInfo) (*ExportInfo, error) {
+	if refInfo, ok := refCheckMap[name]; ok {
+		return refInfo, nil
+	}
+	var found []string
+	for refName, refInfo := range refCheckMap {
+		if refInfo.SheetOption.MessageName == name {
+			found = append(found, refName)
+		}
+	}
+	if len(found) > 1 {
+		sort.Strings(found)
+		return nil, fmt.Errorf("ref message is used by multiple tables ref:%v tables:%v", name, found)
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+	return refCheckMap[found[0]], nil
+}
+
+// 解析#Ref的配置,生成关联检查的目标,配置表名也可以是message名
+func newRefTarget(ref string, refCheckMap map[string]*ExportInfo) (*refTarget, error) {
+	refInfo, err := findRefInfo(ref, refCheckMap)
+	if err != nil {
+		return nil, err
+	}
+	if refInfo != nil {
+		if !isKeyedMgrType(refInfo.SheetOption.MgrType) {
+			return nil, fmt.Errorf("ref must be a map table or use #Ref=%v.FieldName ref:%v", ref, ref)
+		}
+		// 组合key不能直接关联,需要指定字段
+		if isCompositeMapKey(refInfo.SheetOption.MapKeyName) {
+			return nil, fmt.Errorf("ref table has composite key, use #Ref=%v.FieldName ref:%v", ref, ref)
+		}
+		target := &refTarget{
+			values:    make(map[string]struct{}),
+			fieldName: lastFieldName(refInfo.SheetOption.MapKeyName),
+		}
+		rv := reflect.ValueOf(refInfo.MgrData)
+		if rv.Kind() == reflect.Map {
+			for _, key := range rv.MapKeys() {
+				if s, ok := refValueString(key.Interface()); ok {
+					target.values[s] = struct{}{}
+				}
+			}
+		}
+		return target, nil
+	}
+	// Sheet.Field
+	sheetName, fieldName, ok := strings.Cut(ref, ".")
+	if !ok || fieldName == "" {
+		return nil, fmt.Errorf("ref not exists ref:%v", ref)
+	}
+	refInfo, err = findRefInfo(sheetName, refCheckMap)
+	if err != nil {
+		return nil, err
+	}
+	if refInfo == nil {
+		return nil, fmt.Errorf("ref not exists ref:%v", ref)
+	}
+	msgDesc := FindMessageDescriptor(refInfo.SheetOption.MessageName)
+	if msgDesc == nil {
+		return nil, fmt.Errorf("ref message not found ref:%v message:%v", ref, refInfo.SheetOption.MessageName)
+	}
+	path := strings.Split(fieldName, ".")
+	if findRefFieldDescriptor(msgDesc, path) == nil {
+		return nil, fmt.Errorf("ref field not exists ref:%v", ref)
+	}
+	target := &refTarget{
+		values:    make(map[string]struct{}),
+		fieldName: path[len(path)-1],
+	}
+	if refInfo.SheetOption.MgrType == "object" {
+		if row, ok := refInfo.MgrData.(map[string]any); ok {
+			collectRefValues(target, msgDesc, row, path)
+		}
+		return target, nil
+	}
+	rangeRefRows(refInfo.MgrData, func(_ any, _ int, row map[string]any) {
+		collectRefValues(target, msgDesc, row, path)
+	})
+	return target, nil
+}
+
+func collectRefValues(target *refTarget, msgDesc *desc.MessageDescriptor, row map[string]any, path []string) {
+	fieldDesc, value := getRefFieldValue(msgDesc, row, path)
+	if fieldDesc == nil {
+		return
+	}
+	rangeRefValue(fieldDesc, value, "", func(v any) {
+		if s, ok := refValueString(v); ok {
+			target.values[s] = struct{}{}
+		}
+	})
+}
+
+// 遍历map或slice格式的配置数据,map按key排序,保证检查结果的顺序是固定的
+// groupIndex是group类型同一个key的第几行,其他类型都是0
+func rangeRefRows(mgrData any, fn func(rowKey any, groupIndex int, row map[string]any)) {
+	switch t := mgrData.(type) {
+	case []any:
+		for i, row := range t {
+			if m, ok := row.(map[string]any); ok {
+				fn(i, 0, m)
+			}
+		}
+	default:
+		rv := reflect.ValueOf(mgrData)
+		if rv.Kind() != reflect.Map {
+			return
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessMapKey(keys[i], keys[j])
+		})
+		for _, key := range keys {
+			switch elem := rv.MapIndex(key).Interface().(type) {
+			case map[string]any:
+				fn(key.Interface(), 0, elem)
+			case []any:
+				// group类型,一个key对应多行
+				for i, row := range elem {
+					if m, ok := row.(map[string]any); ok {
+						fn(key.Interface(), i, m)
+					}
+				}
+			}
+		}
+	}
+}
+
+// 按字段路径查找字段,路径中的字段名可以是proto字段名或json名
+func findRefFieldDescriptor(msgDesc *desc.MessageDescriptor, path []string) *desc.FieldDescriptor {
+	var fieldDesc *desc.FieldDescriptor
+	for i, name := range path {
+		if i > 0 {
+			if fieldDesc.IsRepeated() || fieldDesc.GetMessageType() == nil {
+				return nil
+			}
+			msgDesc = fieldDesc.GetMessageType()
+		}
+		fieldDesc = msgDesc.FindFieldByName(name)
+		if fieldDesc == nil {
+			fieldDesc = msgDesc.FindFieldByJSONName(name)
+		}
+		if fieldDesc == nil {
+			return nil
+		}
+	}
+	return fieldDesc
+}
+
+// 按字段路径获取一行数据中的值
+func getRefFieldValue(msgDesc *desc.MessageDescriptor, row map[string]any, path []string) (*desc.FieldDescriptor, any) {
+	var (
+		fieldDesc *desc.FieldDescriptor
+		value     any = row
+	)
+	for i, name := range path {
+		if i > 0 {
+			if fieldDesc.IsRepeated() || fieldDesc.GetMessageType() == nil {
+				return nil, nil
+			}
+			msgDesc = fieldDesc.GetMessageType()
+		}
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		fieldDesc = msgDesc.FindFieldByName(name)
+		if fieldDesc == nil {
+			fieldDesc = msgDesc.FindFieldByJSONName(name)
+		}
+		if fieldDesc == nil {
+			return nil, nil
+		}
+		// 展开的子字段使用配置的列名,其他字段使用json名
+		if value, ok = m[name]; !ok {
+			if value, ok = m[fieldDesc.GetJSONName()]; !ok {
+				return nil, nil
+			}
+		}
+	}
+	return fieldDesc, value
+}
+
+// 按字段路径遍历一行数据中的值,路径中间的repeated字段遍历每个元素,如Rewards.CfgId
+// 路径中间的map字段遍历每个value,如IntEventFields.Values,不是value字段名时作为map的key,如IntEventFields.Level
+func rangeRefFieldValue(msgDesc *desc.MessageDescriptor, row map[string]any, path []string, fn func(fieldDesc *desc.FieldDescriptor, value any)) {
+	fieldDesc, value := getRefFieldValue(msgDesc, row, path[:1])
+	if fieldDesc == nil {
+		return
+	}
+	if len(path) == 1 {
+		fn(fieldDesc, value)
+		return
+	}
+	subMsgDesc := getValueMessageType(fieldDesc)
+	if fieldDesc.IsMap() {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Map {
+			return
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessMapKey(keys[i], keys[j])
+		})
+		if subMsgDesc == nil || findRefFieldDescriptor(subMsgDesc, path[1:2]) == nil {
+			for _, key := range keys {
+				if keyStr, _ := refValueString(key.Interface()); keyStr != path[1] {
+					continue
+				}
+				elem := rv.MapIndex(key).Interface()
+				if len(path) == 2 {
+					fn(fieldDesc.GetMapValueType(), elem)
+				} else if m, ok := elem.(map[string]any); ok && subMsgDesc != nil {
+					rangeRefFieldValue(subMsgDesc, m, path[2:], fn)
+				}
+			}
+			return
+		}
+		for _, key := range keys {
+			if m, ok := rv.MapIndex(key).Interface().(map[string]any); ok {
+				rangeRefFieldValue(subMsgDesc, m, path[1:], fn)
+			}
+		}
+		return
+	}
+	if subMsgDesc == nil {
+		return
+	}
+	if !fieldDesc.IsRepeated() {
+		if m, ok := value.(map[string]any); ok {
+			rangeRefFieldValue(subMsgDesc, m, path[1:], fn)
+		}
+		return
+	}
+	list, _ := value.([]any)
+	for _, elem := range list {
+		if m, ok := elem.(map[string]any); ok {
+			rangeRefFieldValue(subMsgDesc, m, path[1:], fn)
+		}
+	}
+}
+
+// 遍历字段中需要检查的值
+// repeated字段遍历每个元素,map字段遍历key,message取fieldName子字段的值
+func rangeRefValue(fieldDesc *desc.FieldDescriptor, value any, fieldName string, fn func(v any)) {
+	if value == nil {
+		return
+	}
+	if fieldDesc.IsMap() {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Map {
+			return
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessMapKey(keys[i], keys[j])
+		})
+		for _, key := range keys {
+			fn(key.Interface())
+		}
+		return
+	}
+	if fieldDesc.IsRepeated() {
+		if list, ok := value.([]any); ok {
+			for _, elem := range list {
+				rangeRefElem(fieldDesc, elem, fieldName, fn)
+			}
+		}
+		return
+	}
+	rangeRefElem(fieldDesc, value, fieldName, fn)
+}
+
+func rangeRefElem(fieldDesc *desc.FieldDescriptor, value any, fieldName string, fn func(v any)) {
+	msgDesc := fieldDesc.GetMessageType()
+	if msgDesc == nil {
+		fn(value)
+		return
+	}
+	m, ok := value.(map[string]any)
+	if !ok || fieldName == "" {
+		return
+	}
+	subFieldDesc, subValue := getRefFieldValue(msgDesc, m, []string{fieldName})
+	if subFieldDesc == nil {
+		return
+	}
+	rangeRefValue(subFieldDesc, subValue, "", fn)
+}
+
+// 把关联检查的值统一转换成字符串,整数类型的key和json格式解析出来的float64可以互相比较
+func refValueString(value any) (string, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+			return strconv.FormatInt(int64(f), 10), true
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), true
+	}
+	return fmt.Sprintf("%v", value), false
+}
+
+func lastFieldName(fieldName string) string {
+	if idx := strings.LastIndex(fieldName, "."); idx >= 0 {
+		return fieldName[idx+1:]
+	}
+	return fieldName
+}
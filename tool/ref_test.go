@@ -0,0 +1,177 @@
+package tool
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckRefs(t *testing.T) {
+	initProtoForTest(t)
+	initTempProtoForTest(t, "types_test.proto", typesTestProto)
+	exportInfoMap := map[string]*ExportInfo{
+		"item.Item": {
+			SheetOption: &SheetOption{SheetName: "Item", MessageName: "ItemCfg", MgrType: "map", MapKeyName: "CfgId"},
+			MgrData: map[int32]any{
+				1: map[string]any{"CfgId": int32(1), "Name": "a"},
+				2: map[string]any{"CfgId": int32(2), "Name": "b"},
+			},
+		},
+		"item.ItemName": {
+			SheetOption: &SheetOption{SheetName: "ItemName", MessageName: "ItemCfg", MgrType: "map", MapKeyName: "Name"},
+			MgrData: map[string]any{
+				"a": map[string]any{"CfgId": int32(1), "Name": "a"},
+				"b": map[string]any{"CfgId": int32(2), "Name": "b"},
+			},
+		},
+		"item.Unique": {
+			SheetOption: &SheetOption{SheetName: "Unique", MessageName: "DelElemArg", MgrType: "map", MapKeyName: "UniqueId"},
+			MgrData: map[int64]any{
+				10000000001: map[string]any{"UniqueId": int64(10000000001)},
+			},
+		},
+		"quest.Quest": {
+			SheetOption: &SheetOption{
+				SheetName:   "Quest",
+				MessageName: "QuestCfg",
+				MgrType:     "slice",
+				ColumnOpts: []*ColumnOption{
+					{Name: "PreQuest", Ref: "Quest.CfgId"},
+					{Name: "NextQuests", Ref: "Quest.CfgId"},
+					{Name: "Rewards", Ref: "Item", Merge: true},
+					{Name: "Rewards", Ref: "Item", Merge: true},
+					{Name: "Properties", Ref: "ItemName"},
+					{Name: "Progress.Event", Ref: "ItemName.Name", ExpandName: "Progress", ExpandFieldName: "Event"},
+					{Name: "Progress.IntEventFields.Values", Ref: "Item"},
+					{Name: "Progress.StringEventFields.Kill", Ref: "ItemName"},
+					{Name: "Category", Ref: "Item", Format: "json"},
+					{Name: "Detail", Ref: "NotExists"},
+				},
+			},
+			MgrData: []any{
+				map[string]any{
+					"CfgId":      int32(1),
+					"PreQuest":   int32(2),
+					"NextQuests": []any{int32(2), int32(3)},
+					"Rewards":    []any{map[string]any{"CfgId": int32(2), "Num": int32(1)}, map[string]any{"CfgId": int32(9)}},
+					"Properties": map[string]any{"a": "1", "c": "2"},
+					"Progress": map[string]any{"Event": "b", "IntEventFields": map[string]any{
+						"Level": map[string]any{"Op": ">=", "Values": []any{int32(1), int32(7)}},
+					}, "StringEventFields": map[string]any{"Kill": "x", "Other": "y"}},
+					"Category": float64(1),
+					"Detail":   "x",
+				},
+				map[string]any{
+					"CfgId":    int32(2),
+					"PreQuest": int32(5),
+					"Progress": map[string]any{"Event": "z"},
+					"Category": float64(3),
+				},
+			},
+		},
+		"types.Types": {
+			SheetOption: &SheetOption{
+				SheetName:   "Types",
+				MessageName: "TestCfgTypes",
+				MgrType:     "map",
+				MapKeyName:  "CfgId",
+				ColumnOpts: []*ColumnOption{
+					{Name: "Int64Value", Ref: "Unique"},
+					{Name: "Uint64Value", Ref: "Unique"},
+					{Name: "Int32Values", Ref: "Quest"},
+				},
+			},
+			MgrData: map[int32]any{
+				1: map[string]any{"CfgId": int32(1), "Int64Value": int64(10000000001), "Uint64Value": uint64(10000000002), "Int32Values": []any{int32(1)}},
+			},
+		},
+	}
+	orderNames := []string{"item.Item", "item.ItemName", "item.Unique", "quest.Quest", "types.Types"}
+	refCheckMap := make(map[string]*ExportInfo)
+	for _, name := range orderNames {
+		exportInfo := exportInfoMap[name]
+		refCheckMap[exportInfo.SheetOption.SheetName] = exportInfo
+	}
+	diags := NewDiagnostics()
+	checkRefs(exportInfoMap, orderNames, refCheckMap, diags)
+	var got []string
+	for _, diag := range diags.Items() {
+		if diag.Severity != SeverityError {
+			t.Fatalf("unexpected severity: %v", diag)
+		}
+		got = append(got, diag.SheetName+" "+diag.Column+" "+diag.Message)
+	}
+	want := []string{
+		"Quest PreQuest ref ERROR ref:Quest.CfgId checkId:5 row:1",
+		"Quest NextQuests ref ERROR ref:Quest.CfgId checkId:3 row:0",
+		"Quest Rewards ref ERROR ref:Item checkId:9 row:0",
+		"Quest Properties ref ERROR ref:ItemName checkId:c row:0",
+		"Quest Progress.Event ref ERROR ref:ItemName.Name checkId:z row:1",
+		"Quest Progress.IntEventFields.Values ref ERROR ref:Item checkId:7 row:0",
+		"Quest Progress.StringEventFields.Kill ref ERROR ref:ItemName checkId:x row:0",
+		"Quest Category ref ERROR ref:Item checkId:3 row:1",
+		"Quest Detail ref not exists ref:NotExists",
+		"Types Uint64Value ref ERROR ref:Unique checkId:10000000002 row:1",
+		"Types Int32Values ref must be a map table or use #Ref=Quest.FieldName ref:Quest",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestCheckRefsCell(t *testing.T) {
+	initProtoForTest(t)
+	itemInfo := &ExportInfo{
+		SheetOption: &SheetOption{SheetName: "Item", MessageName: "ItemCfg", MgrType: "map", MapKeyName: "CfgId"},
+		MgrData: map[int32]any{
+			1: map[string]any{"CfgId": int32(1)},
+		},
+	}
+	// 两个sheet合并成一个slice,合并后的下标1来自第二个sheet
+	questColumns := func(refIndex int) []*ColumnOption {
+		return []*ColumnOption{
+			{Name: "CfgId", ColumnIndex: 0},
+			{Name: "PreQuest", ColumnIndex: refIndex, Ref: "Item"},
+		}
+	}
+	quest1 := &SheetOption{ExcelName: "quest1.xlsx", SheetName: "Quest", MessageName: "QuestCfg", MgrType: "slice",
+		ColumnOpts: questColumns(1), RowIndexes: map[string][]int{"0": {3}}}
+	quest2 := &SheetOption{ExcelName: "quest2.xlsx", SheetName: "Quest", MessageName: "QuestCfg", MgrType: "slice",
+		ColumnOpts: questColumns(2), RowIndexes: map[string][]int{"0": {4}}}
+	questInfo := &ExportInfo{
+		SheetOption: quest1,
+		MgrData: []any{
+			map[string]any{"CfgId": int32(1), "PreQuest": int32(1)},
+			map[string]any{"CfgId": int32(2), "PreQuest": int32(5)},
+		},
+	}
+	questInfo.addRowSources(quest1, 0)
+	questInfo.addRowSources(quest2, 1)
+	// group类型,同一个key的第2行
+	drop := &SheetOption{ExcelName: "drop.xlsx", SheetName: "Drop", MessageName: "QuestCfg", MgrType: "group", MapKeyName: "CfgId",
+		ColumnOpts: questColumns(1), RowIndexes: map[string][]int{"1": {2, 5}}}
+	dropInfo := &ExportInfo{
+		SheetOption: drop,
+		MgrData: map[int32]any{
+			1: []any{
+				map[string]any{"CfgId": int32(1), "PreQuest": int32(1)},
+				map[string]any{"CfgId": int32(1), "PreQuest": int32(8)},
+			},
+		},
+	}
+	dropInfo.addRowSources(drop, 0)
+	exportInfoMap := map[string]*ExportInfo{"Item": itemInfo, "Quest": questInfo, "Drop": dropInfo}
+	orderNames := []string{"Item", "Quest", "Drop"}
+	diags := NewDiagnostics()
+	checkRefs(exportInfoMap, orderNames, exportInfoMap, diags)
+	var got []string
+	for _, diag := range diags.Items() {
+		got = append(got, diag.ExcelName+" "+diag.SheetName+" "+diag.Cell+" "+diag.Message)
+	}
+	want := []string{
+		"quest2.xlsx Quest C5 ref ERROR ref:Item checkId:5 row:1",
+		"drop.xlsx Drop B6 ref ERROR ref:Item checkId:8 row:1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
@@ -0,0 +1,265 @@
+package tool
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 配置表的数据源文件,如xlsx文件,csv文件
+type SourceFile interface {
+	// 按行读取sheet的数据
+	Rows(sheetName string) (SourceRows, error)
+	Close() error
+}
+
+// 按行读取的数据
+type SourceRows interface {
+	Next() bool
+	Columns() ([]string, error)
+	Close() error
+}
+
+// 根据扩展名打开数据源文件
+// .csv和.tsv是文本格式,其他的都作为excel打开
+func OpenSourceFile(fileName string) (SourceFile, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return openCsvSource(fileName, ',')
+	case ".tsv":
+		return openCsvSource(fileName, '\t')
+	}
+	f, err := excelize.OpenFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return NewExcelSource(f), nil
+}
+
+// 是否是支持的数据源文件
+func IsSourceFile(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".xlsx", ".csv", ".tsv":
+		return true
+	}
+	return false
+}
+
+// excel文件
+type excelSource struct {
+	file *excelize.File
+}
+
+func NewExcelSource(f *excelize.File) SourceFile {
+	return &excelSource{file: f}
+}
+
+func (s *excelSource) Rows(sheetName string) (SourceRows, error) {
+	rows, err := s.file.Rows(sheetName)
+	if err != nil {
+		return nil, err
+	}
+	date1904 := false
+	if props, err := s.file.GetWorkbookProps(); err == nil && props.Date1904 != nil {
+		date1904 = *props.Date1904
+	}
+	return &excelRows{
+		file:       s.file,
+		sheetName:  sheetName,
+		rows:       rows,
+		date1904:   date1904,
+		dateStyles: make(map[int]bool),
+	}, nil
+}
+
+func (s *excelSource) Close() error {
+	return s.file.Close()
+}
+
+type excelRows struct {
+	file        *excelize.File
+	sheetName   string
+	rows        *excelize.Rows
+	rowNum      int          // 当前行号,从1开始
+	date1904    bool         // 1904日期系统
+	dateStyles  map[int]bool // 样式id是否是日期时间格式
+	calcFormula bool         // 计算没有缓存值的公式
+}
+
+func (r *excelRows) Next() bool {
+	r.rowNum++
+	return r.rows.Next()
+}
+
+// 读取单元格的原始值,见convertCellValue
+func (r *excelRows) Columns() ([]string, error) {
+	row, err := r.rows.Columns(excelize.Options{RawCellValue: true})
+	if err != nil {
+		return row, err
+	}
+	for columnIndex, value := range row {
+		if value == "" && !r.calcFormula {
+			continue
+		}
+		cellName, err := excelize.CoordinatesToCellName(columnIndex+1, r.rowNum)
+		if err != nil {
+			return row, err
+		}
+		if value == "" {
+			if value, err = r.calcFormulaCell(cellName); err != nil {
+				return row, fmt.Errorf("calc formula cell:%v err:%w", cellName, err)
+			}
+			if value == "" {
+				continue
+			}
+		}
+		row[columnIndex] = r.convertCellValue(cellName, value)
+	}
+	return row, nil
+}
+
+func (r *excelRows) Close() error {
+	return r.rows.Close()
+}
+
+// csv和tsv文件,只有一个sheet,读取时忽略sheet名
+type csvSource struct {
+	fileName string
+	comma    rune
+}
+
+func openCsvSource(fileName string, comma rune) (SourceFile, error) {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errors.New("not a file: " + fileName)
+	}
+	return &csvSource{
+		fileName: fileName,
+		comma:    comma,
+	}, nil
+}
+
+func (s *csvSource) Rows(sheetName string) (SourceRows, error) {
+	file, err := os.Open(s.fileName)
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(file)
+	// 跳过utf8的BOM,excel另存为的csv文件会带BOM
+	if bom, err := reader.Peek(3); err == nil && string(bom) == "\xEF\xBB\xBF" {
+		_, _ = reader.Discard(3)
+	}
+	csvReader := csv.NewReader(reader)
+	csvReader.Comma = s.comma
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+	return &csvRows{
+		file:   file,
+		reader: csvReader,
+	}, nil
+}
+
+func (s *csvSource) Close() error {
+	return nil
+}
+
+type csvRows struct {
+	file   *os.File
+	reader *csv.Reader
+	row    []string
+	err    error
+}
+
+func (r *csvRows) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	row, err := r.reader.Read()
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		// 在Columns中返回错误
+		r.err = err
+		r.row = nil
+		return true
+	}
+	r.row = row
+	return true
+}
+
+func (r *csvRows) Columns() ([]string, error) {
+	return r.row, r.err
+}
+
+func (r *csvRows) Close() error {
+	return r.file.Close()
+}
+
+// 竖表:第一列是字段名,后面的每一列是一条数据,适合字段多数据少的配置表
+//
+//	-------------------------------
+//	| CfgId     | 1     | 2     |
+//	| Name      | boss1 | boss2 |
+//	| Skills    | 1;2   | 3     |
+//	-------------------------------
+const layoutVertical = "vertical"
+
+// 竖表的数据,读取时转换成按行读取
+type transposedRows struct {
+	rows  [][]string
+	index int
+}
+
+// 读取所有的行并行列互换,原来的rows读取完后关闭
+func newTransposedRows(rows SourceRows) (SourceRows, error) {
+	var columns [][]string
+	rowCount := 0
+	for rows.Next() {
+		row, err := rows.Columns()
+		if err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		for columnIndex, cell := range row {
+			for len(columns) <= columnIndex {
+				columns = append(columns, nil)
+			}
+			if cell == "" {
+				continue
+			}
+			for len(columns[columnIndex]) < rowCount {
+				columns[columnIndex] = append(columns[columnIndex], "")
+			}
+			columns[columnIndex] = append(columns[columnIndex], cell)
+		}
+		rowCount++
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return &transposedRows{rows: columns, index: -1}, nil
+}
+
+func (r *transposedRows) Next() bool {
+	r.index++
+	return r.index < len(r.rows)
+}
+
+func (r *transposedRows) Columns() ([]string, error) {
+	return r.rows[r.index], nil
+}
+
+func (r *transposedRows) Close() error {
+	return nil
+}
@@ -0,0 +1,94 @@
+package tool
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvertSourceSheet(t *testing.T) {
+	initTempProtoForTest(t, "types_test.proto", typesTestProto)
+	rows := [][]string{
+		{"#注释行", "", "", ""},
+		{"##var", "CfgId", "Int32Values", "#备注"},
+		{"##type", "int", "int[]", ""},
+		{"", "1", "1;2", "a,b"},
+		{"", "2", "3", "c\"d"},
+	}
+	toLines := func(sep string, quote bool) string {
+		lines := make([]string, 0, len(rows))
+		for _, row := range rows {
+			cells := make([]string, 0, len(row))
+			for _, cell := range row {
+				if quote && strings.ContainsAny(cell, ",\"") {
+					cell = "\"" + strings.ReplaceAll(cell, "\"", "\"\"") + "\""
+				}
+				cells = append(cells, cell)
+			}
+			lines = append(lines, strings.Join(cells, sep))
+		}
+		return strings.Join(lines, "\r\n")
+	}
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "types.csv")
+	tsvFile := filepath.Join(dir, "types.tsv")
+	// excel另存为的csv带BOM
+	if err := os.WriteFile(csvFile, []byte("\xEF\xBB\xBF"+toLines(",", true)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tsvFile, []byte(toLines("\t", false)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	excelRows := make([][]interface{}, 0, len(rows))
+	for _, row := range rows {
+		excelRow := make([]interface{}, 0, len(row))
+		for _, cell := range row {
+			excelRow = append(excelRow, cell)
+		}
+		excelRows = append(excelRows, excelRow)
+	}
+	f := newTestSheetFile(t, "TestCfgTypes", excelRows...)
+	defer func() { _ = f.Close() }()
+
+	newOpt := func() *SheetOption {
+		return &SheetOption{
+			SheetName:   "TestCfgTypes",
+			MessageName: "TestCfgTypes",
+			MgrType:     "map",
+		}
+	}
+	want, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), newOpt(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := want.(map[int32]any); len(m) != 2 {
+		t.Fatalf("expected 2 rows, got %v", m)
+	}
+	for _, fileName := range []string{csvFile, tsvFile} {
+		source, err := OpenSourceFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		diags := NewDiagnostics()
+		// csv文件只有一个sheet,sheet名不影响读取
+		got, err := ConvertSourceSheet(&ExportOption{}, source, newOpt(), diags)
+		_ = source.Close()
+		if err != nil {
+			t.Fatalf("file:%v err:%v", fileName, err)
+		}
+		if len(diags.Items()) > 0 {
+			t.Errorf("file:%v unexpected diagnostics:%v", fileName, diags.Items())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("file:%v expected %v, got %v", fileName, want, got)
+		}
+	}
+}
+
+func TestOpenSourceFileNotExist(t *testing.T) {
+	if _, err := OpenSourceFile(filepath.Join(t.TempDir(), "none.csv")); err == nil {
+		t.Error("expected error for missing csv")
+	}
+}
@@ -0,0 +1,180 @@
+package tool
+
+import (
+	"testing"
+)
+
+// 测试各种基础类型的解析
+const typesTestProto = `syntax = "proto3";
+package typestest;
+import "cfg.proto";
+
+message TestCfgTypes {
+  int32 CfgId = 1;
+  int64 Int64Value = 2;
+  uint32 Uint32Value = 3;
+  uint64 Uint64Value = 4;
+  float FloatValue = 5;
+  double DoubleValue = 6;
+  bool BoolValue = 7;
+  gserver.Color ColorValue = 8;
+  repeated int32 Int32Values = 9;
+}
+`
+
+func TestParseIntCell(t *testing.T) {
+	tests := []struct {
+		input   string
+		bitSize int
+		want    int64
+		wantErr bool
+	}{
+		{"12", 32, 12, false},
+		{"-12", 32, -12, false},
+		{"100.0", 32, 100, false},
+		{"100.00", 64, 100, false},
+		{"12a", 32, 0, true},
+		{"1.5", 32, 0, true},
+		{"2147483648", 32, 0, true},
+		{"2147483648", 64, 2147483648, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseIntCell(tt.input, tt.bitSize)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("input:%v bitSize:%v err:%v", tt.input, tt.bitSize, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("input:%v expected %v, got %v", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestParseUintCell(t *testing.T) {
+	tests := []struct {
+		input   string
+		bitSize int
+		want    uint64
+		wantErr bool
+	}{
+		{"12", 32, 12, false},
+		{"12.0", 32, 12, false},
+		{"-1", 32, 0, true},
+		{"4294967296", 32, 0, true},
+		{"4294967296", 64, 4294967296, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseUintCell(tt.input, tt.bitSize)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("input:%v bitSize:%v err:%v", tt.input, tt.bitSize, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("input:%v expected %v, got %v", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestParseBoolCell(t *testing.T) {
+	for _, input := range []string{"true", "TRUE", "1"} {
+		if b, err := ParseBoolCell(input); err != nil || !b {
+			t.Errorf("input:%v expected true, got %v err:%v", input, b, err)
+		}
+	}
+	for _, input := range []string{"false", "False", "0"} {
+		if b, err := ParseBoolCell(input); err != nil || b {
+			t.Errorf("input:%v expected false, got %v err:%v", input, b, err)
+		}
+	}
+	if _, err := ParseBoolCell("yes"); err == nil {
+		t.Error("expected error for yes")
+	}
+}
+
+func TestConvertFieldValueStrict(t *testing.T) {
+	initTempProtoForTest(t, "types_test.proto", typesTestProto)
+	msgDesc := FindMessageDescriptor("TestCfgTypes")
+	tests := []struct {
+		fieldName string
+		input     string
+		want      any
+	}{
+		{"CfgId", "100.0", int32(100)},
+		{"CfgId", "12a", nil},
+		{"CfgId", "1.5", nil},
+		{"CfgId", "3000000000", nil},
+		{"Int64Value", "3000000000", int64(3000000000)},
+		{"Uint32Value", "-1", nil},
+		{"Uint64Value", "18446744073709551615", uint64(18446744073709551615)},
+		{"FloatValue", "1.5", float32(1.5)},
+		{"DoubleValue", "abc", nil},
+		{"BoolValue", "yes", nil},
+		{"BoolValue", "TRUE", true},
+		{"ColorValue", "Color_Red", int32(1)},
+		{"ColorValue", "2", int32(2)},
+		{"ColorValue", "Color_Purple", nil},
+	}
+	for _, tt := range tests {
+		fieldDesc := msgDesc.FindFieldByName(tt.fieldName)
+		columnOpt := &ColumnOption{Name: tt.fieldName, ColumnIndex: 1}
+		diags := NewDiagnostics()
+		ctx := &CellContext{
+			Diags:     diags,
+			SheetName: "TestCfgTypes",
+			RowIndex:  2,
+			Column:    columnOpt,
+			Strict:    true,
+		}
+		got := convertFieldValue(ctx, fieldDesc, columnOpt, tt.input)
+		if got != tt.want {
+			t.Errorf("field:%v input:%v expected %v(%T), got %v(%T)", tt.fieldName, tt.input, tt.want, tt.want, got, got)
+		}
+		if tt.want == nil {
+			items := diags.Items()
+			if len(items) != 1 || items[0].Severity != SeverityError || items[0].Cell != "B3" {
+				t.Errorf("field:%v input:%v unexpected diagnostics:%v", tt.fieldName, tt.input, items)
+				continue
+			}
+			t.Logf("%v", items[0])
+		} else if len(diags.Items()) > 0 {
+			t.Errorf("field:%v input:%v unexpected diagnostics:%v", tt.fieldName, tt.input, diags.Items())
+		}
+	}
+}
+
+func TestConvertFieldValueNotStrict(t *testing.T) {
+	initTempProtoForTest(t, "types_test.proto", typesTestProto)
+	msgDesc := FindMessageDescriptor("TestCfgTypes")
+	diags := NewDiagnostics()
+	ctx := &CellContext{Diags: diags}
+	// 非严格模式下保持原有的转换结果,但是会输出警告
+	got := convertFieldValue(ctx, msgDesc.FindFieldByName("CfgId"), &ColumnOption{Name: "CfgId"}, "12a")
+	if got != int32(0) {
+		t.Errorf("expected 0, got %v", got)
+	}
+	got = convertFieldValue(ctx, msgDesc.FindFieldByName("BoolValue"), &ColumnOption{Name: "BoolValue"}, "yes")
+	if got != false {
+		t.Errorf("expected false, got %v", got)
+	}
+	if diags.Count(SeverityWarning) != 2 || diags.HasError() {
+		t.Errorf("unexpected diagnostics:%v", diags.Items())
+	}
+}
+
+func TestSetFieldValueStrictRepeated(t *testing.T) {
+	initTempProtoForTest(t, "types_test.proto", typesTestProto)
+	fieldDesc := FindMessageDescriptor("TestCfgTypes").FindFieldByName("Int32Values")
+	diags := NewDiagnostics()
+	ctx := &CellContext{Diags: diags, Strict: true}
+	m := make(map[string]any)
+	if err := setFieldValue(ctx, m, fieldDesc, &ColumnOption{Name: "Int32Values"}, "1;x;3.0", false); err != nil {
+		t.Fatal(err)
+	}
+	values := m["Int32Values"].([]any)
+	if len(values) != 2 || values[0] != int32(1) || values[1] != int32(3) {
+		t.Errorf("unexpected values:%v", values)
+	}
+	if diags.Count(SeverityError) != 1 {
+		t.Errorf("unexpected diagnostics:%v", diags.Items())
+	}
+}
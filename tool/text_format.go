@@ -0,0 +1,201 @@
+package tool
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// 导出为yaml格式,和json的数据一致,map的key有序
+func marshalToYaml(v any) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// 导出为protobuf的文本格式,便于review
+// object: 整个文件就是一个message
+// map,slice,group: 每行数据是一个message,用注释标记key或者序号,行之间空一行
+// 字段按字段编号输出,map按key排序,输出的内容是稳定的,可以直接比较文本
+func marshalToPrototext(v any, sheetOption *SheetOption) ([]byte, error) {
+	msgDesc := FindMessageDescriptor(sheetOption.MessageName)
+	if msgDesc == nil {
+		return nil, fmt.Errorf("message %s not found", sheetOption.MessageName)
+	}
+	msgType := msgDesc.UnwrapMessage()
+	buffer := bytes.NewBuffer(nil)
+	writeRowFn := func(comment string, row any) error {
+		msg, err := toDynamicProtoMessage(msgType, row)
+		if err != nil {
+			return err
+		}
+		if buffer.Len() > 0 {
+			buffer.WriteString("\n")
+		}
+		if comment != "" {
+			buffer.WriteString(comment)
+			buffer.WriteString("\n")
+		}
+		writePrototextMessage(buffer, msg.ProtoReflect(), "")
+		return nil
+	}
+	switch sheetOption.MgrType {
+	case "map":
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map {
+			return nil, fmt.Errorf("invalid map data type: %T", v)
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessMapKey(keys[i], keys[j])
+		})
+		for _, key := range keys {
+			if err := writeRowFn(fmt.Sprintf("# %v", key.Interface()), rv.MapIndex(key).Interface()); err != nil {
+				return nil, err
+			}
+		}
+	case "group":
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map {
+			return nil, fmt.Errorf("invalid group data type: %T", v)
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessMapKey(keys[i], keys[j])
+		})
+		for _, key := range keys {
+			groupRows, ok := rv.MapIndex(key).Interface().([]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid group data type: %T", rv.MapIndex(key).Interface())
+			}
+			for i, row := range groupRows {
+				if err := writeRowFn(fmt.Sprintf("# %v [%v]", key.Interface(), i), row); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case "slice":
+		dataSlice, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid slice data type: %T", v)
+		}
+		for i, row := range dataSlice {
+			if err := writeRowFn(fmt.Sprintf("# [%v]", i), row); err != nil {
+				return nil, err
+			}
+		}
+	case "object":
+		if err := writeRowFn("", v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported MgrType: %s", sheetOption.MgrType)
+	}
+	return buffer.Bytes(), nil
+}
+
+// 按字段编号输出message的文本格式,没有设置的字段不输出
+// prototext.Marshal会随机插入空格,所以这里自己输出
+func writePrototextMessage(buffer *bytes.Buffer, msg protoreflect.Message, indent string) {
+	fields := msg.Descriptor().Fields()
+	fieldDescs := make([]protoreflect.FieldDescriptor, 0, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		fieldDescs = append(fieldDescs, fields.Get(i))
+	}
+	sort.Slice(fieldDescs, func(i, j int) bool {
+		return fieldDescs[i].Number() < fieldDescs[j].Number()
+	})
+	for _, fd := range fieldDescs {
+		if !msg.Has(fd) {
+			continue
+		}
+		value := msg.Get(fd)
+		switch {
+		case fd.IsList():
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				writePrototextField(buffer, fd.TextName(), fd, list.Get(i), indent)
+			}
+		case fd.IsMap():
+			mapValue := value.Map()
+			keys := make([]protoreflect.MapKey, 0, mapValue.Len())
+			mapValue.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+				keys = append(keys, key)
+				return true
+			})
+			sort.Slice(keys, func(i, j int) bool {
+				return lessMapKey(reflect.ValueOf(keys[i].Interface()), reflect.ValueOf(keys[j].Interface()))
+			})
+			for _, key := range keys {
+				buffer.WriteString(indent + fd.TextName() + " {\n")
+				writePrototextField(buffer, "key", fd.MapKey(), key.Value(), indent+"  ")
+				writePrototextField(buffer, "value", fd.MapValue(), mapValue.Get(key), indent+"  ")
+				buffer.WriteString(indent + "}\n")
+			}
+		default:
+			writePrototextField(buffer, fd.TextName(), fd, value, indent)
+		}
+	}
+}
+
+// 输出一个字段值,repeated和map的元素也用这个函数输出
+func writePrototextField(buffer *bytes.Buffer, name string, fd protoreflect.FieldDescriptor, value protoreflect.Value, indent string) {
+	if fd.Message() != nil {
+		buffer.WriteString(indent + name + " {\n")
+		writePrototextMessage(buffer, value.Message(), indent+"  ")
+		buffer.WriteString(indent + "}\n")
+		return
+	}
+	buffer.WriteString(indent + name + ": " + formatPrototextScalar(fd, value) + "\n")
+}
+
+func formatPrototextScalar(fd protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return strconv.FormatBool(value.Bool())
+	case protoreflect.EnumKind:
+		if enumValue := fd.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return strconv.FormatInt(int64(value.Enum()), 10)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(value.Int(), 10)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(value.Uint(), 10)
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f := value.Float()
+		switch {
+		case math.IsNaN(f):
+			return "nan"
+		case math.IsInf(f, 1):
+			return "inf"
+		case math.IsInf(f, -1):
+			return "-inf"
+		}
+		bitSize := 64
+		if fd.Kind() == protoreflect.FloatKind {
+			bitSize = 32
+		}
+		return strconv.FormatFloat(f, 'g', -1, bitSize)
+	case protoreflect.StringKind:
+		return strconv.Quote(value.String())
+	case protoreflect.BytesKind:
+		return strconv.Quote(string(value.Bytes()))
+	}
+	return fmt.Sprintf("%v", value.Interface())
+}
@@ -0,0 +1,108 @@
+package tool
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestMarshalToYaml(t *testing.T) {
+	data := map[int32]any{
+		10: map[string]any{"Name": "b", "CfgId": int32(10)},
+		2:  map[string]any{"Name": "a", "CfgId": int32(2), "Values": []any{int32(1), int32(2)}},
+	}
+	got, err := marshalToYaml(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2:\n  CfgId: 2\n  Name: a\n  Values:\n    - 1\n    - 2\n10:\n  CfgId: 10\n  Name: b\n"
+	if string(got) != want {
+		t.Errorf("expected:\n%v\ngot:\n%v", want, string(got))
+	}
+}
+
+const textFormatTestProto = `syntax = "proto3";
+package textformattest;
+import "cfg.proto";
+
+message TestTextFormat {
+  string Name = 3;
+  int32 CfgId = 1;
+  map<int32, string> Names = 5;
+  repeated gserver.ItemNum Items = 4;
+  float FloatValue = 2;
+  gserver.Color ColorValue = 6;
+  bytes Data = 7;
+}
+`
+
+func TestMarshalToPrototext(t *testing.T) {
+	initTempProtoForTest(t, "text_format_test.proto", textFormatTestProto)
+	row := func(cfgId int32) map[string]any {
+		return map[string]any{
+			"CfgId":      cfgId,
+			"Name":       "n\"1\"\n",
+			"FloatValue": float32(1.5),
+			"ColorValue": int32(1),
+			"Names":      map[int32]any{10: "b", 2: "a", -1: "c"},
+			"Items":      []any{map[string]any{"CfgId": int32(1), "Num": int32(2)}, map[string]any{"CfgId": int32(3)}},
+			"Data":       []byte{0, 'a'},
+		}
+	}
+	rowText := func(cfgId string) string {
+		return `CfgId: ` + cfgId + `
+FloatValue: 1.5
+Name: "n\"1\"\n"
+Items {
+  CfgId: 1
+  Num: 2
+}
+Items {
+  CfgId: 3
+}
+Names {
+  key: -1
+  value: "c"
+}
+Names {
+  key: 2
+  value: "a"
+}
+Names {
+  key: 10
+  value: "b"
+}
+ColorValue: Color_Red
+Data: "\x00a"
+`
+	}
+	tests := []struct {
+		mgrType string
+		data    any
+		want    string
+	}{
+		{"map", map[int32]any{2: row(2), 1: row(1)}, "# 1\n" + rowText("1") + "\n# 2\n" + rowText("2")},
+		{"slice", []any{row(2), row(1)}, "# [0]\n" + rowText("2") + "\n# [1]\n" + rowText("1")},
+		{"object", row(3), rowText("3")},
+		{"object", map[string]any{}, ""},
+	}
+	msgType := FindMessageDescriptor("TestTextFormat").UnwrapMessage()
+	for _, tt := range tests {
+		got, err := marshalToPrototext(tt.data, &SheetOption{MessageName: "TestTextFormat", MgrType: tt.mgrType})
+		if err != nil {
+			t.Fatalf("mgrType:%v err:%v", tt.mgrType, err)
+		}
+		if !bytes.Equal(got, []byte(tt.want)) {
+			t.Errorf("mgrType:%v expected:\n%v\ngot:\n%v", tt.mgrType, tt.want, string(got))
+		}
+		// object输出的文本就是一个message,可以被prototext解析
+		if tt.mgrType != "object" {
+			continue
+		}
+		if err := prototext.Unmarshal(got, dynamicpb.NewMessage(msgType)); err != nil {
+			t.Errorf("unmarshal %q err:%v", got, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package tool
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConvertSheetVertical(t *testing.T) {
+	initProtoForTest(t)
+	toJson := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	f := newTestSheetFile(t, "Quest",
+		[]interface{}{"CfgId", "1", "#注释列", "2"},
+		[]interface{}{"#说明", "任务1", "", "任务2"},
+		[]interface{}{"Name", "a", "x", "b"},
+		[]interface{}{"NextQuests", "2;3"},
+		[]interface{}{},
+		[]interface{}{"Progress#Format=json", `{"Type":1}`, "", `{"Total":2}`},
+		[]interface{}{"Rewards#Field=no", "10_1;11_2", "", "x_1"},
+	)
+	defer func() { _ = f.Close() }()
+	for _, mgrType := range []string{"map", "slice"} {
+		diags := NewDiagnostics()
+		opt := &SheetOption{SheetName: "Quest", MessageName: "QuestCfg", MgrType: mgrType, Layout: "vertical"}
+		data, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), opt, diags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		row1 := `{"CfgId":1,"Name":"a","NextQuests":[2,3],"Progress":{"Type":1},"Rewards":[{"CfgId":10,"Num":1},{"CfgId":11,"Num":2}]}`
+		row2 := `{"CfgId":2,"Name":"b","Progress":{"Total":2},"Rewards":[{"CfgId":0,"Num":1}]}`
+		want := `[` + row1 + `,` + row2 + `]`
+		if mgrType == "map" {
+			want = `{"1":` + row1 + `,"2":` + row2 + `}`
+		}
+		if got := toJson(data); got != want {
+			t.Fatalf("%v got:\n%v\nwant:\n%v", mgrType, got, want)
+		}
+		if _, err = marshalToProtoBinary(data, opt); err != nil {
+			t.Fatal(err)
+		}
+		// 诊断信息的单元格坐标是竖表中的坐标
+		if len(diags.Items()) != 1 || diags.Items()[0].Cell != "D7" {
+			t.Fatalf("%v unexpected diags: %v", mgrType, diags.Items())
+		}
+	}
+
+	opt := &SheetOption{SheetName: "Quest", MessageName: "QuestCfg", MgrType: "object", Layout: "vertical"}
+	if _, err := ConvertSourceSheet(&ExportOption{}, NewExcelSource(f), opt, NewDiagnostics()); err == nil {
+		t.Fatal("expect unsupported Layout error")
+	}
+}
@@ -0,0 +1,162 @@
+package tool
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// 监控的文件状态
+type watchFileState struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// 是否是需要监控的文件
+// excel编辑时会生成~$开头的锁文件,保存时会生成临时文件,这些文件都忽略
+func isWatchFile(fileName string) bool {
+	baseName := filepath.Base(fileName)
+	if strings.HasPrefix(baseName, "~$") || strings.HasPrefix(baseName, ".") {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(baseName)) {
+	case ".proto":
+		return true
+	}
+	return IsSourceFile(baseName)
+}
+
+// 扫描目录下需要监控的文件
+func scanWatchFiles(dirs ...string) map[string]watchFileState {
+	files := make(map[string]watchFileState)
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !isWatchFile(path) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			files[filepath.Clean(path)] = watchFileState{
+				ModTime: info.ModTime(),
+				Size:    info.Size(),
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// 返回新增,修改,删除的文件
+func diffWatchFiles(oldFiles, newFiles map[string]watchFileState) []string {
+	var changed []string
+	for fileName, newState := range newFiles {
+		if oldState, ok := oldFiles[fileName]; !ok || oldState != newState {
+			changed = append(changed, fileName)
+		}
+	}
+	for fileName := range oldFiles {
+		if _, ok := newFiles[fileName]; !ok {
+			changed = append(changed, fileName)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// 监控DataImportPath和ProtoPath,文件有变化时自动重新导出
+// 保存文件时可能会连续修改多次,等到一次轮询没有新的变化时才导出
+// 只重新转换变化的excel(没有设置CacheFile时使用内存缓存),内容没有变化的导出文件不再写入
+func WatchAndExport(options *ExportOption, interval time.Duration, stop <-chan struct{}) error {
+	checkExportOption(options)
+	options.watching = true
+	if err := runWatchExport(options, nil); err != nil {
+		color.Red("export err:%v", err)
+	}
+	// 只有首次导出忽略缓存
+	options.Force = false
+	lastFiles := scanWatchFiles(options.DataImportPath, options.ProtoPath)
+	pendingFiles := make(map[string]struct{})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	fmt.Println(fmt.Sprintf("watching %v %v", options.DataImportPath, options.ProtoPath))
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+		curFiles := scanWatchFiles(options.DataImportPath, options.ProtoPath)
+		changed := diffWatchFiles(lastFiles, curFiles)
+		lastFiles = curFiles
+		if len(changed) > 0 {
+			for _, fileName := range changed {
+				pendingFiles[fileName] = struct{}{}
+			}
+			continue
+		}
+		if len(pendingFiles) == 0 {
+			continue
+		}
+		changedFiles := make([]string, 0, len(pendingFiles))
+		for fileName := range pendingFiles {
+			changedFiles = append(changedFiles, fileName)
+		}
+		sort.Strings(changedFiles)
+		pendingFiles = make(map[string]struct{})
+		if err := runWatchExport(options, changedFiles); err != nil {
+			// 导出失败时继续监控,等待下一次修改
+			color.Red("export err:%v", err)
+		}
+	}
+}
+
+// changedFiles为空表示首次导出,输出所有的诊断信息
+// 否则只输出变化的excel相关的诊断信息
+func runWatchExport(options *ExportOption, changedFiles []string) error {
+	protoChanged := changedFiles == nil
+	changedExcels := make(map[string]struct{})
+	for _, fileName := range changedFiles {
+		fmt.Println(fmt.Sprintf("file changed:%v", fileName))
+		if strings.EqualFold(filepath.Ext(fileName), ".proto") {
+			protoChanged = true
+			continue
+		}
+		relName, err := filepath.Rel(filepath.Clean(options.DataImportPath), fileName)
+		if err != nil {
+			relName = filepath.Base(fileName)
+		}
+		changedExcels[filepath.ToSlash(relName)] = struct{}{}
+	}
+	if protoChanged && len(options.ProtoFiles) > 0 {
+		ResetProtoFile()
+		if err := ParseProtoFile([]string{options.ProtoPath}, options.ProtoFiles...); err != nil {
+			return err
+		}
+	}
+	diags, err := ExportAllWithDiagnostics(options, options.ExportAllExcelFile, options.ExportAllSheet)
+	// 总表或者proto变化时,所有的sheet都受影响
+	_, exportAllChanged := changedExcels[options.ExportAllExcelFile]
+	if !protoChanged && !exportAllChanged {
+		diags = diags.Filter(func(diag *Diagnostic) bool {
+			_, ok := changedExcels[diag.ExcelName]
+			return ok
+		})
+	}
+	diags.Print()
+	if err != nil {
+		return err
+	}
+	fmt.Println(fmt.Sprintf("Diagnostics errors:%v warnings:%v", diags.Count(SeverityError), diags.Count(SeverityWarning)))
+	fmt.Println(fmt.Sprintf("Export Success %v", time.Now().Format(time.DateTime)))
+	return nil
+}
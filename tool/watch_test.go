@@ -0,0 +1,145 @@
+package tool
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsWatchFile(t *testing.T) {
+	tests := []struct {
+		fileName string
+		want     bool
+	}{
+		{"data/excel/itemcfg.xlsx", true},
+		{"proto/cfg.proto", true},
+		{"data/excel/bigcfg.csv", true},
+		{"data/excel/bigcfg.TSV", true},
+		{"data/excel/~$itemcfg.xlsx", false},
+		{"data/excel/.itemcfg.xlsx", false},
+		{"data/excel/A1B2C3D4", false},
+		{"data/excel/itemcfg.tmp", false},
+	}
+	for _, tt := range tests {
+		if got := isWatchFile(tt.fileName); got != tt.want {
+			t.Errorf("fileName:%v expected %v, got %v", tt.fileName, tt.want, got)
+		}
+	}
+}
+
+func TestScanAndDiffWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("a.xlsx", "a")
+	writeFile("b.xlsx", "b")
+	oldFiles := scanWatchFiles(dir)
+	if len(oldFiles) != 2 {
+		t.Fatalf("expected 2 files, got %v", oldFiles)
+	}
+
+	// 锁文件的变化不触发导出
+	writeFile("~$a.xlsx", "lock")
+	if changed := diffWatchFiles(oldFiles, scanWatchFiles(dir)); len(changed) != 0 {
+		t.Errorf("expected no change, got %v", changed)
+	}
+
+	writeFile("a.xlsx", "a2")
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "a.xlsx"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	writeFile("c.xlsx", "c")
+	if err := os.Remove(filepath.Join(dir, "b.xlsx")); err != nil {
+		t.Fatal(err)
+	}
+	changed := diffWatchFiles(oldFiles, scanWatchFiles(dir))
+	want := []string{filepath.Join(dir, "a.xlsx"), filepath.Join(dir, "b.xlsx"), filepath.Join(dir, "c.xlsx")}
+	if len(changed) != len(want) {
+		t.Fatalf("expected %v, got %v", want, changed)
+	}
+	for i := range want {
+		if changed[i] != want[i] {
+			t.Errorf("expected %v, got %v", want[i], changed[i])
+		}
+	}
+}
+
+func TestRunWatchExport(t *testing.T) {
+	initProtoForTest(t)
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+	outDir := filepath.Join(dir, "out")
+	for _, d := range []string{dataDir, outDir} {
+		if err := os.MkdirAll(d, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "exportall.csv"),
+		[]byte("Excel,Sheet,Message,MgrType\na.xlsx,LevelA,LevelExp,slice\nb.xlsx,LevelB,LevelExp,slice\n"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	saveExcel := func(fileName, sheetName, needExp string) {
+		f := newTestSheetFile(t, sheetName,
+			[]interface{}{"Level", "NeedExp"},
+			[]interface{}{"1", needExp},
+		)
+		defer func() { _ = f.Close() }()
+		if err := f.SaveAs(filepath.Join(dataDir, fileName)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saveExcel("a.xlsx", "LevelA", "100")
+	saveExcel("b.xlsx", "LevelB", "200")
+	options := &ExportOption{
+		DataImportPath:     dataDir,
+		DataExportPath:     []string{outDir},
+		ExportAllExcelFile: "exportall.csv",
+		ExportAllSheet:     "ExportAll",
+		watching:           true,
+	}
+	checkExportOption(options)
+	if err := runWatchExport(options, nil); err != nil {
+		t.Fatal(err)
+	}
+	if options.watchCacheData == nil {
+		t.Fatal("expected memory cache")
+	}
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, name := range []string{"LevelA.json", "LevelB.json"} {
+		if err := os.Chtimes(filepath.Join(outDir, name), past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 只修改b.xlsx,只有LevelB.json重新写入
+	saveExcel("b.xlsx", "LevelB", "300")
+	if err := runWatchExport(options, []string{filepath.Join(dataDir, "b.xlsx")}); err != nil {
+		t.Fatal(err)
+	}
+	modTime := func(name string) time.Time {
+		info, err := os.Stat(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.ModTime()
+	}
+	if !modTime("LevelA.json").Equal(past) {
+		t.Error("LevelA.json should not be rewritten")
+	}
+	if modTime("LevelB.json").Equal(past) {
+		t.Error("LevelB.json should be rewritten")
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, "LevelB.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "300") {
+		t.Errorf("LevelB.json not updated: %s", data)
+	}
+}
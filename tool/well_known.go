@@ -0,0 +1,148 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jhump/protoreflect/desc"
+)
+
+// well-known types的proto文件(如google/protobuf/timestamp.proto)已经内置在protoparse中,import时不需要放到ProtoPath中
+// 单元格的值转换成protojson的格式,导出pb时由protojson解析
+
+// Timestamp支持的时间格式,没有时区的时间使用ExportOption.TimeZone
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006-01-02T15:04:05",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+}
+
+// Timestamp字段的时区,为空时使用本地时区
+func (opt *ExportOption) GetTimeLocation() (*time.Location, error) {
+	if opt.TimeZone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(opt.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TimeZone %v:%w", opt.TimeZone, err)
+	}
+	return loc, nil
+}
+
+// 转换well-known types的单元格,不是well-known types时返回false
+func convertWellKnownValue(ctx *CellContext, fieldDesc *desc.FieldDescriptor, columnOption *ColumnOption, cellValue string) (any, bool) {
+	msgDesc := fieldDesc.GetMessageType()
+	if msgDesc == nil {
+		return nil, false
+	}
+	switch msgDesc.GetFullyQualifiedName() {
+	case "google.protobuf.Timestamp":
+		t, err := parseTimestampCell(cellValue, ctx.getLocation())
+		if err != nil {
+			ctx.parseFailed("field:%v invalid timestamp value %q", fieldDesc.GetName(), cellValue)
+			return nil, true
+		}
+		return t.UTC().Format(time.RFC3339Nano), true
+
+	case "google.protobuf.Duration":
+		d, err := parseDurationCell(cellValue)
+		if err != nil {
+			ctx.parseFailed("field:%v invalid duration value %q", fieldDesc.GetName(), cellValue)
+			return nil, true
+		}
+		return formatProtoDuration(d), true
+
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		// 包装类型:单元格为空时不导出(null),填了0也会导出,用于区分没有配置和配置了0
+		return convertFieldValue(ctx, msgDesc.FindFieldByName("value"), columnOption, cellValue), true
+
+	case "google.protobuf.Struct", "google.protobuf.ListValue", "google.protobuf.Value":
+		var jsonValue any
+		if err := json.Unmarshal([]byte(cellValue), &jsonValue); err != nil {
+			if msgDesc.GetName() != "Value" {
+				ctx.parseFailed("field:%v invalid json value %q", fieldDesc.GetName(), cellValue)
+				return nil, true
+			}
+			jsonValue = cellValue // Value可以直接填字符串
+		}
+		switch jsonValue.(type) {
+		case map[string]any:
+			if msgDesc.GetName() == "ListValue" {
+				ctx.parseFailed("field:%v invalid json array %q", fieldDesc.GetName(), cellValue)
+				return nil, true
+			}
+		case []any:
+			if msgDesc.GetName() == "Struct" {
+				ctx.parseFailed("field:%v invalid json object %q", fieldDesc.GetName(), cellValue)
+				return nil, true
+			}
+		default:
+			if msgDesc.GetName() != "Value" {
+				ctx.parseFailed("field:%v invalid json value %q", fieldDesc.GetName(), cellValue)
+				return nil, true
+			}
+		}
+		return jsonValue, true
+
+	case "google.protobuf.Empty":
+		return make(map[string]any), true
+	}
+	return nil, false
+}
+
+// 解析时间,支持2024-02-19 10:00:00,2024-02-19,RFC3339格式(带时区)和unix时间戳(秒)
+func parseTimestampCell(cellValue string, loc *time.Location) (time.Time, error) {
+	if IsDigit(cellValue) {
+		seconds, err := strconv.ParseInt(cellValue, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(seconds, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, cellValue); err == nil {
+		return t, nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, cellValue, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", cellValue)
+}
+
+// 解析时间间隔,支持1h30m,90s,1.5h,数字表示秒
+func parseDurationCell(cellValue string) (time.Duration, error) {
+	if IsDigit(cellValue) {
+		seconds, err := strconv.ParseFloat(cellValue, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	return time.ParseDuration(cellValue)
+}
+
+// protojson的Duration格式,如5400s,1.5s,-0.001s
+func formatProtoDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	seconds := int64(d / time.Second)
+	nanos := int64(d % time.Second)
+	if nanos == 0 {
+		return fmt.Sprintf("%v%vs", sign, seconds)
+	}
+	return fmt.Sprintf("%v%v.%vs", sign, seconds, strings.TrimRight(fmt.Sprintf("%09d", nanos), "0"))
+}
@@ -0,0 +1,98 @@
+package tool
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+const wellKnownTestProto = `syntax = "proto3";
+package wellknowntest;
+
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/duration.proto";
+import "google/protobuf/wrappers.proto";
+import "google/protobuf/struct.proto";
+
+message WellKnownCfg {
+  int32 CfgId = 1;
+  google.protobuf.Timestamp StartTime = 2;
+  google.protobuf.Duration Cd = 3;
+  google.protobuf.Int32Value Limit = 4;
+  google.protobuf.StringValue Title = 5;
+  google.protobuf.Struct Extra = 6;
+  google.protobuf.Value Any = 7;
+  repeated google.protobuf.Duration Cds = 8;
+}
+`
+
+func TestFormatProtoDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{90 * time.Minute, "5400s"},
+		{1500 * time.Millisecond, "1.5s"},
+		{-time.Millisecond, "-0.001s"},
+		{0, "0s"},
+	}
+	for _, tt := range tests {
+		if got := formatProtoDuration(tt.d); got != tt.want {
+			t.Errorf("formatProtoDuration(%v)=%v want:%v", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestConvertSheetWellKnownTypes(t *testing.T) {
+	initTempProtoForTest(t, "well_known_test.proto", wellKnownTestProto)
+	f := newTestSheetFile(t, "WellKnown",
+		[]interface{}{"CfgId", "StartTime", "Cd", "Limit", "Title", "Extra", "Any", "Cds"},
+		[]interface{}{"1", "2024-02-19 10:00:00", "1h30m", "0", "", `{"a":1,"b":"x"}`, "abc", "1m;1.5"},
+		[]interface{}{"2", "2024-02-19T10:00:00+09:00", "90", "", "t", "", "[1,2]"},
+		[]interface{}{"3", "1708308000", "", "5"},
+		[]interface{}{"4", "2024-13-01", "x", "", "", "[1]"},
+	)
+	defer func() { _ = f.Close() }()
+	diags := NewDiagnostics()
+	opt := &SheetOption{SheetName: "WellKnown", MessageName: "WellKnownCfg", MgrType: "slice"}
+	data, err := ConvertSourceSheet(&ExportOption{TimeZone: "Asia/Shanghai"}, NewExcelSource(f), opt, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Int32Value填0时导出0,为空时不导出
+	want := `[{"Any":"abc","Cd":"5400s","Cds":["60s","1.5s"],"CfgId":1,"Extra":{"a":1,"b":"x"},"Limit":0,"StartTime":"2024-02-19T02:00:00Z"},` +
+		`{"Any":[1,2],"Cd":"90s","CfgId":2,"StartTime":"2024-02-19T01:00:00Z","Title":"t"},` +
+		`{"CfgId":3,"Limit":5,"StartTime":"2024-02-19T02:00:00Z"},` +
+		`{"CfgId":4}]`
+	if string(jsonData) != want {
+		t.Fatalf("got:\n%v\nwant:\n%v", string(jsonData), want)
+	}
+	if _, err = marshalToProtoBinary(data, opt); err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, diag := range diags.Items() {
+		messages = append(messages, diag.Cell+" "+diag.Message)
+	}
+	wantMessages := []string{
+		`B5 field:StartTime invalid timestamp value "2024-13-01"`,
+		`C5 field:Cd invalid duration value "x"`,
+		`F5 field:Extra invalid json object "[1]"`,
+	}
+	if len(messages) != len(wantMessages) {
+		t.Fatalf("got diags:\n%v", messages)
+	}
+	for i := range messages {
+		if messages[i] != wantMessages[i] {
+			t.Fatalf("got diags:\n%v\nwant:\n%v", messages, wantMessages)
+		}
+	}
+
+	if _, err = ConvertSourceSheet(&ExportOption{TimeZone: "Bad/Zone"}, NewExcelSource(f), opt, NewDiagnostics()); err == nil {
+		t.Fatal("expect invalid TimeZone error")
+	}
+}